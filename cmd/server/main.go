@@ -8,18 +8,63 @@ import (
 	"log"
 	"net/http"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/moustafa/home24/internal/analyzer"
 	"github.com/moustafa/home24/internal/handler"
+	"github.com/moustafa/home24/internal/jobs"
+	"github.com/moustafa/home24/internal/netguard"
 )
 
 func main() {
 	port := flag.Int("port", 8080, "HTTP listen port")
+	hostConcurrency := flag.Int("host-concurrency", 4, "maximum concurrent link checks per host")
+	hostDelay := flag.Duration("host-delay", 0, "minimum delay between link checks to the same host")
+	maxRetryAfter := flag.Duration("max-retry-after", 10*time.Second, "longest Retry-After waited out on 429/503 link checks; longer ones, or any when 0, end the check")
+	linkAttempts := flag.Int("link-attempts", 3, "maximum attempts per link check, including the first")
+	longRedirectChain := flag.Int("long-redirect-chain", analyzer.DefaultLongRedirectChain, "redirect hops above which a chain is flagged as too long")
+	allow := flag.String("allow", "", "comma-separated IPs, CIDR prefixes and hostnames (*.example.com) exempt from the private-address block")
+	workers := flag.Int("workers", jobs.DefaultWorkers, "analysis jobs run at once")
+	queueDepth := flag.Int("queue-depth", jobs.DefaultQueueDepth, "analysis jobs that may wait for a worker")
+	jobTTL := flag.Duration("job-ttl", jobs.DefaultTTL, "how long finished analysis jobs are kept")
+	batchConcurrency := flag.Int("batch-concurrency", handler.DefaultBatchConcurrency, "pages analyzed at once across all batch and crawl requests")
 	flag.Parse()
 
+	guard, err := netguard.New(strings.Split(*allow, ","))
+	if err != nil {
+		log.Fatalf("invalid -allow: %v", err)
+	}
+
+	checker := analyzer.NewLinkChecker()
+	checker.PerHost = *hostConcurrency
+	checker.HostDelay = *hostDelay
+	checker.MaxRetryAfter = *maxRetryAfter
+	checker.Retry.MaxAttempts = *linkAttempts
+	checker.LongRedirectChain = *longRedirectChain
+
+	jobManager := jobs.New(jobs.Config{
+		Workers:    *workers,
+		QueueDepth: *queueDepth,
+		TTL:        *jobTTL,
+	})
+
+	h := handler.New(handler.Config{
+		LinkChecker:       checker,
+		LongRedirectChain: *longRedirectChain,
+		Guard:             guard,
+		Jobs:              jobManager,
+		BatchConcurrency:  *batchConcurrency,
+	})
+
 	mux := http.NewServeMux()
-	mux.HandleFunc("/api/analyze", handler.Analyze)
+	mux.HandleFunc("/api/analyze", h.Analyze)
+	mux.HandleFunc("/api/analyze/stream", h.Stream)
+	mux.HandleFunc("/api/batch", h.Batch)
+	mux.HandleFunc("/api/crawl", h.Crawl)
+	mux.HandleFunc("/api/jobs", h.CreateJob)
+	mux.HandleFunc("/api/jobs/{id}", h.Job)
 
 	addr := fmt.Sprintf(":%d", *port)
 	srv := &http.Server{Addr: addr, Handler: mux}
@@ -42,5 +87,8 @@ func main() {
 	if err := srv.Shutdown(shutdownCtx); err != nil {
 		log.Fatalf("shutdown error: %v", err)
 	}
+	if err := jobManager.Close(shutdownCtx); err != nil {
+		log.Fatalf("shutdown error: %v", err)
+	}
 	log.Println("server stopped")
 }
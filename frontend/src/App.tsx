@@ -1,15 +1,16 @@
 import { useState } from 'react';
-import { analyzeUrl } from './api/analyze';
+import { analyzeUrlStream } from './api/stream';
 import { UrlForm } from './components/UrlForm';
 import { ResultsTable } from './components/ResultsTable';
 import { ErrorMessage } from './components/ErrorMessage';
 import { Spinner } from './components/Spinner';
-import type { AnalyzeResponse, ErrorResponse } from './types';
+import { ProgressBar } from './components/ProgressBar';
+import type { AnalyzeResponse, ErrorResponse, Progress } from './types';
 import './App.css';
 
 type State =
   | { status: 'idle' }
-  | { status: 'loading' }
+  | { status: 'loading'; progress?: Progress; partial?: AnalyzeResponse }
   | { status: 'success'; data: AnalyzeResponse }
   | { status: 'error'; error: ErrorResponse };
 
@@ -19,7 +20,13 @@ function App() {
   const handleSubmit = async (url: string) => {
     setState({ status: 'loading' });
     try {
-      const data = await analyzeUrl(url);
+      const data = await analyzeUrlStream(url, (progress) =>
+        setState((prev) => ({
+          status: 'loading',
+          progress,
+          partial: progress.result ?? (prev.status === 'loading' ? prev.partial : undefined),
+        })),
+      );
       setState({ status: 'success', data });
     } catch (err) {
       if (err && typeof err === 'object' && 'statusCode' in err) {
@@ -37,7 +44,9 @@ function App() {
     <div className="app">
       <h1>Page Insight</h1>
       <UrlForm onSubmit={handleSubmit} loading={state.status === 'loading'} />
-      {state.status === 'loading' && <Spinner />}
+      {state.status === 'loading' &&
+        (state.progress ? <ProgressBar progress={state.progress} /> : <Spinner />)}
+      {state.status === 'loading' && state.partial && <ResultsTable data={state.partial} />}
       {state.status === 'success' && <ResultsTable data={state.data} />}
       {state.status === 'error' && <ErrorMessage error={state.error} />}
     </div>
@@ -0,0 +1,38 @@
+import type { AnalyzeRequest, AnalyzeResponse, ErrorResponse, Progress } from '../types';
+
+// analyzeUrlStream analyzes url over server-sent events, calling onProgress as
+// each phase completes, and resolves with the full result. options are the
+// other fields of an analyze request.
+export function analyzeUrlStream(
+  url: string,
+  onProgress: (p: Progress) => void,
+  options?: Omit<AnalyzeRequest, 'url'>,
+): Promise<AnalyzeResponse> {
+  return new Promise((resolve, reject) => {
+    const params = new URLSearchParams({ url });
+    if (options) {
+      params.set('options', JSON.stringify(options));
+    }
+    const source = new EventSource(`/api/analyze/stream?${params}`);
+    const on = <T,>(event: string, fn: (data: T) => void) =>
+      source.addEventListener(event, (e) => fn(JSON.parse((e as MessageEvent).data)));
+
+    source.addEventListener('fetched', () => onProgress({ phase: 'fetched' }));
+    on<Progress>('parsed', onProgress);
+    on<Progress>('static', onProgress);
+    on<Progress>('links', onProgress);
+    on<AnalyzeResponse>('done', (data) => {
+      source.close();
+      resolve(data);
+    });
+    on<ErrorResponse>('failed', (err) => {
+      source.close();
+      reject(err);
+    });
+    // Only transport failures reach onerror; analysis errors arrive as failed.
+    source.onerror = () => {
+      source.close();
+      reject({ statusCode: 0, message: 'The connection to the server was lost.' });
+    };
+  });
+}
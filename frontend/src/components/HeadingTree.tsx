@@ -0,0 +1,19 @@
+import type { HeadingNode } from '../types';
+
+interface Props {
+  headings: HeadingNode[];
+}
+
+export function HeadingTree({ headings }: Props) {
+  return (
+    <ul className="heading-tree">
+      {headings.map((h, i) => (
+        <li key={i}>
+          <span className="heading-level">H{h.level}</span>{' '}
+          {h.text || <em>Empty</em>}
+          {h.children.length > 0 && <HeadingTree headings={h.children} />}
+        </li>
+      ))}
+    </ul>
+  );
+}
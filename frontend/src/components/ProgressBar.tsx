@@ -0,0 +1,32 @@
+import type { Progress } from '../types';
+
+interface Props {
+  progress: Progress;
+}
+
+const phaseLabels: Record<Progress['phase'], string> = {
+  fetched: 'Page fetched, parsing…',
+  parsed: 'Parsed, analyzing…',
+  static: 'Checking links…',
+  links: 'Checking links…',
+};
+
+export function ProgressBar({ progress }: Props) {
+  const { checked = 0, total = 0 } = progress;
+  const linkShare = total > 0 ? checked / total : 0;
+  // Fetching and parsing take the first fifth of the bar, link checks the rest.
+  const fraction =
+    progress.phase === 'fetched' ? 0.1 : progress.phase === 'parsed' ? 0.2 : 0.2 + 0.8 * linkShare;
+
+  return (
+    <div className="progress">
+      <div className="progress-track">
+        <div className="progress-fill" style={{ width: `${Math.round(fraction * 100)}%` }} />
+      </div>
+      <small>
+        {phaseLabels[progress.phase]}
+        {total > 0 && ` ${checked} of ${total}`}
+      </small>
+    </div>
+  );
+}
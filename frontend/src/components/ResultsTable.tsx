@@ -1,4 +1,5 @@
-import type { AnalyzeResponse } from '../types';
+import type { AnalyzeResponse, LengthCheck, ResourceKind } from '../types';
+import { HeadingTree } from './HeadingTree';
 
 interface Props {
   data: AnalyzeResponse;
@@ -6,7 +7,30 @@ interface Props {
 
 const headingOrder = ['h1', 'h2', 'h3', 'h4', 'h5', 'h6'];
 
+const resourceOrder: ResourceKind[] = [
+  'anchor',
+  'image',
+  'script',
+  'stylesheet',
+  'media',
+  'frame',
+  'form',
+];
+
+function lengthLabel(c: LengthCheck) {
+  return `${c.length} chars (${c.status.replace('_', ' ')})`;
+}
+
+function formatBytes(n: number) {
+  if (n < 1024) return `${n} B`;
+  if (n < 1024 * 1024) return `${(n / 1024).toFixed(1)} KiB`;
+  return `${(n / 1024 / 1024).toFixed(1)} MiB`;
+}
+
 export function ResultsTable({ data }: Props) {
+  const brokenLinks = data.links.filter((l) => !l.accessible && l.error !== 'rate_limited');
+  const resourceKinds = resourceOrder.filter((k) => data.resources[k]);
+
   return (
     <div className="results">
       <table>
@@ -15,13 +39,195 @@ export function ResultsTable({ data }: Props) {
             <th>HTML Version</th>
             <td>{data.htmlVersion}</td>
           </tr>
+          <tr>
+            <th>Encoding</th>
+            <td>
+              {data.encoding.detected} (from {data.encoding.source})
+              {data.encoding.mismatch && (
+                <strong>
+                  {' '}
+                  Declarations disagree: header {data.encoding.header ?? 'none'}, meta{' '}
+                  {data.encoding.meta ?? 'none'}
+                  {data.encoding.bom && `, BOM ${data.encoding.bom}`}
+                </strong>
+              )}
+            </td>
+          </tr>
           <tr>
             <th>Page Title</th>
             <td>{data.title || <em>No title</em>}</td>
           </tr>
+          {data.xhtml && (
+            <tr>
+              <th>XHTML</th>
+              <td>
+                {data.xhtml.wellFormed
+                  ? 'Well-formed'
+                  : data.xhtml.errors.map((e, i) => (
+                      <div key={i}>
+                        Line {e.line}, column {e.column}: {e.message}
+                      </div>
+                    ))}
+              </td>
+            </tr>
+          )}
+          {data.transfer && (
+            <tr>
+              <th>Transfer</th>
+              <td>
+                {formatBytes(data.transfer.compressedBytes)}
+                {data.transfer.contentEncoding &&
+                  ` ${data.transfer.contentEncoding} → ${formatBytes(data.transfer.decompressedBytes)}`}
+                , first byte after {data.transfer.ttfbMs} ms, done after {data.transfer.downloadMs} ms
+                {data.transfer.truncated && (
+                  <strong> Truncated at {formatBytes(data.transfer.limitBytes)}</strong>
+                )}
+              </td>
+            </tr>
+          )}
+          {data.pageRedirects && (
+            <tr>
+              <th>Redirects</th>
+              <td>
+                {data.pageRedirects.hops.map((h, i) => (
+                  <div key={i}>
+                    {h.statusCode} {h.url} → {h.location}
+                  </div>
+                ))}
+                {data.pageRedirects.downgrade && <strong>HTTPS → HTTP downgrade</strong>}
+                {data.pageRedirects.tooLong && <strong>Long redirect chain</strong>}
+              </td>
+            </tr>
+          )}
+        </tbody>
+      </table>
+
+      <h3>Metadata</h3>
+      <table>
+        <tbody>
+          <tr>
+            <th>Title Length</th>
+            <td>{lengthLabel(data.metadata.titleLength)}</td>
+          </tr>
+          <tr>
+            <th>Description</th>
+            <td>
+              {data.metadata.description || <em>No description</em>}
+              <br />
+              <small>{lengthLabel(data.metadata.descriptionLength)}</small>
+            </td>
+          </tr>
+          <tr>
+            <th>Canonical</th>
+            <td>{data.metadata.canonical || <em>None</em>}</td>
+          </tr>
+          <tr>
+            <th>Robots</th>
+            <td>{data.metadata.robots || <em>None</em>}</td>
+          </tr>
+          <tr>
+            <th>Viewport</th>
+            <td>{data.metadata.viewport || <em>None</em>}</td>
+          </tr>
+          <tr>
+            <th>Charset</th>
+            <td>{data.metadata.charset || <em>None</em>}</td>
+          </tr>
+          {data.metadata.keywords.length > 0 && (
+            <tr>
+              <th>Keywords</th>
+              <td>{data.metadata.keywords.join(', ')}</td>
+            </tr>
+          )}
+          {data.metadata.hreflang.length > 0 && (
+            <tr>
+              <th>Hreflang</th>
+              <td>
+                {data.metadata.hreflang.map((h) => (
+                  <div key={h.lang + h.url}>
+                    {h.lang}: {h.url}
+                  </div>
+                ))}
+              </td>
+            </tr>
+          )}
+        </tbody>
+      </table>
+
+      <h3>Social Sharing</h3>
+      <table>
+        <tbody>
+          <tr>
+            <th>Missing Properties</th>
+            <td>{data.social.missing.join(', ') || <em>None</em>}</td>
+          </tr>
+          {data.social.invalid.map((issue, i) => (
+            <tr key={i}>
+              <th>{issue.property}</th>
+              <td>
+                {issue.value || <em>empty</em>} — {issue.reason}
+              </td>
+            </tr>
+          ))}
+          {data.social.images.map((img) => (
+            <tr key={img.url}>
+              <th>{img.text}</th>
+              <td>
+                {img.url} ({img.accessible ? 'reachable' : img.statusCode ?? img.error})
+              </td>
+            </tr>
+          ))}
         </tbody>
       </table>
 
+      <h3>Structured Data</h3>
+      {data.structuredData.entities.length === 0 &&
+        data.structuredData.errors.length === 0 && <em>None found</em>}
+      {(data.structuredData.entities.length > 0 ||
+        data.structuredData.errors.length > 0) && (
+        <table>
+          <tbody>
+            {data.structuredData.entities.map((e, i) => (
+              <tr key={i}>
+                <th>{e.types.join(', ') || <em>Untyped</em>}</th>
+                <td>
+                  {e.source}
+                  {e.missing.length > 0 && ` — missing ${e.missing.join(', ')}`}
+                </td>
+              </tr>
+            ))}
+            {data.structuredData.errors.map((err, i) => (
+              <tr key={`err-${i}`}>
+                <th>
+                  JSON-LD #{err.script} ({err.line}:{err.column})
+                </th>
+                <td>{err.message}</td>
+              </tr>
+            ))}
+          </tbody>
+        </table>
+      )}
+
+      <h3>Accessibility</h3>
+      {data.accessibility.length === 0 ? (
+        <em>No issues found</em>
+      ) : (
+        <table className="link-list">
+          <tbody>
+            {data.accessibility.map((f, i) => (
+              <tr key={i}>
+                <th>
+                  {f.severity}: {f.rule}
+                </th>
+                <td>
+                  {f.message} <code>{f.path}</code>
+                </td>
+              </tr>
+            ))}
+          </tbody>
+        </table>
+      )}
+
       <h3>Headings</h3>
       <table>
         <thead>
@@ -39,29 +245,163 @@ export function ResultsTable({ data }: Props) {
           </tr>
         </tbody>
       </table>
+      {data.outline.headings.length > 0 && <HeadingTree headings={data.outline.headings} />}
+      {data.outline.issues.length > 0 && (
+        <ul>
+          {data.outline.issues.map((issue, i) => (
+            <li key={i}>{issue.message}</li>
+          ))}
+        </ul>
+      )}
 
       <h3>Links</h3>
       <table>
         <tbody>
           <tr>
             <th>Internal Links</th>
-            <td>{data.internalLinks}</td>
+            <td>
+              {data.internalLinks} ({data.uniqueInternalLinks} unique)
+            </td>
           </tr>
           <tr>
             <th>External Links</th>
-            <td>{data.externalLinks}</td>
+            <td>
+              {data.externalLinks} ({data.uniqueExternalLinks} unique)
+            </td>
           </tr>
           <tr>
             <th>Inaccessible Links</th>
-            <td>{data.inaccessibleLinks}</td>
+            <td>
+              {data.inaccessibleLinks} ({data.uniqueInaccessibleLinks} unique)
+            </td>
           </tr>
         </tbody>
       </table>
 
+      {resourceKinds.length > 0 && (
+        <>
+          <h3>Resources</h3>
+          <table>
+            <thead>
+              <tr>
+                <th>Kind</th>
+                <th>Total</th>
+                <th>Unique</th>
+                <th>Internal</th>
+                <th>External</th>
+                <th>Inaccessible</th>
+              </tr>
+            </thead>
+            <tbody>
+              {resourceKinds.map((k) => {
+                const c = data.resources[k]!;
+                return (
+                  <tr key={k}>
+                    <th>{k}</th>
+                    <td>{c.total}</td>
+                    <td>{c.unique}</td>
+                    <td>{c.internal}</td>
+                    <td>{c.external}</td>
+                    <td>{c.inaccessible}</td>
+                  </tr>
+                );
+              })}
+            </tbody>
+          </table>
+        </>
+      )}
+
+      {brokenLinks.length > 0 && (
+        <>
+          <h3>Inaccessible Resources</h3>
+          <table className="link-list">
+            <thead>
+              <tr>
+                <th>URL</th>
+                <th>Text</th>
+                <th>Kind</th>
+                <th>Type</th>
+                <th>Occurrences</th>
+                <th>Status</th>
+              </tr>
+            </thead>
+            <tbody>
+              {brokenLinks.map((l, i) => (
+                <tr key={i}>
+                  <td>
+                    <a href={l.url} target="_blank" rel="noreferrer">
+                      {l.url}
+                    </a>
+                  </td>
+                  <td>{l.text || <em>No text</em>}</td>
+                  <td>{l.kind}</td>
+                  <td>{l.isInternal ? 'Internal' : 'External'}</td>
+                  <td>{l.occurrences}</td>
+                  <td>{l.statusCode ?? l.error}</td>
+                </tr>
+              ))}
+            </tbody>
+          </table>
+        </>
+      )}
+
+      {data.fragments.broken.length > 0 && (
+        <>
+          <h3>Broken In-Page Anchors</h3>
+          <table className="link-list">
+            <thead>
+              <tr>
+                <th>Link</th>
+                <th>Text</th>
+              </tr>
+            </thead>
+            <tbody>
+              {data.fragments.broken.map((f, i) => (
+                <tr key={i}>
+                  <td>{f.href}</td>
+                  <td>{f.text || <em>No text</em>}</td>
+                </tr>
+              ))}
+            </tbody>
+          </table>
+        </>
+      )}
+
       <h3>Login Form</h3>
       <span className={`badge ${data.hasLoginForm ? 'badge-yes' : 'badge-no'}`}>
         {data.hasLoginForm ? 'Yes' : 'No'}
       </span>
+      {data.login && (
+        <p>
+          {Math.round(data.login.confidence * 100)}% confidence
+          {data.login.identifierFirst && ', identifier-first'}
+          {data.login.outsideForm && ', outside a form'}: {data.login.signals.join(', ')}
+        </p>
+      )}
+
+      <h3>Forms</h3>
+      {data.forms.length === 0 ? (
+        <em>None found</em>
+      ) : (
+        <table className="link-list">
+          <tbody>
+            {data.forms.map((f, i) => (
+              <tr key={i}>
+                <th>
+                  {f.kind.replace('_', ' ')} ({Math.round(f.confidence * 100)}%)
+                </th>
+                <td>
+                  {f.method} {f.action}
+                  {f.plainHttp && ' — submits over plain HTTP'}
+                  <br />
+                  {f.inputs.length} inputs, {f.hasCsrfToken ? 'CSRF token' : 'no CSRF token'}
+                  {f.signals.length > 0 && ` — ${f.signals.join(', ')}`}
+                </td>
+              </tr>
+            ))}
+          </tbody>
+        </table>
+      )}
     </div>
   );
 }
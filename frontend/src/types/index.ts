@@ -1,18 +1,284 @@
+export interface Scope {
+  mode: 'host' | 'domain';
+  aliases?: string[];
+}
+
+export interface NormalizeOptions {
+  stripTrailingSlash: boolean;
+  sortQuery: boolean;
+}
+
+export interface MetadataLimits {
+  titleMin: number;
+  titleMax: number;
+  descriptionMin: number;
+  descriptionMax: number;
+}
+
 export interface AnalyzeRequest {
   url: string;
+  scope?: Scope;
+  normalize?: NormalizeOptions;
+  metadataLimits?: MetadataLimits;
+  requiredProperties?: Record<string, string[]>;
+}
+
+export type ResourceKind =
+  | 'anchor'
+  | 'image'
+  | 'script'
+  | 'stylesheet'
+  | 'media'
+  | 'frame'
+  | 'form';
+
+export interface ResourceCounts {
+  total: number;
+  internal: number;
+  external: number;
+  inaccessible: number;
+  unique: number;
+  uniqueInternal: number;
+  uniqueExternal: number;
+  uniqueInaccessible: number;
+}
+
+export interface RedirectHop {
+  url: string;
+  statusCode: number;
+  location: string;
+}
+
+export interface RedirectChain {
+  hops: RedirectHop[];
+  loop: boolean;
+  downgrade: boolean;
+  tooLong: boolean;
+}
+
+export interface LinkResult {
+  url: string;
+  text: string;
+  kind: ResourceKind;
+  isInternal: boolean;
+  occurrences: number;
+  accessible: boolean;
+  method?: string;
+  statusCode?: number;
+  error?: string;
+  redirects: number;
+  redirectChain?: RedirectChain;
+  attempts: number;
+  latencyMs: number;
+}
+
+export interface BrokenFragment {
+  href: string;
+  fragment: string;
+  text: string;
+}
+
+export interface FragmentReport {
+  checked: number;
+  broken: BrokenFragment[];
+}
+
+export interface LengthCheck {
+  length: number;
+  min: number;
+  max: number;
+  status: 'ok' | 'missing' | 'too_short' | 'too_long';
+}
+
+export interface Metadata {
+  description: string;
+  robots: string;
+  canonical: string;
+  viewport: string;
+  charset: string;
+  keywords: string[];
+  hreflang: { lang: string; url: string }[];
+  titleLength: LengthCheck;
+  descriptionLength: LengthCheck;
+}
+
+export interface SocialIssue {
+  property: string;
+  value: string;
+  reason: string;
+}
+
+export interface SocialMetadata {
+  openGraph: Record<string, string[]>;
+  article: Record<string, string[]>;
+  product: Record<string, string[]>;
+  twitter: Record<string, string[]>;
+  missing: string[];
+  invalid: SocialIssue[];
+  images: LinkResult[];
+}
+
+export interface Entity {
+  types: string[];
+  source: 'json-ld' | 'microdata';
+  properties: Record<string, unknown>;
+  missing: string[];
+}
+
+export interface StructuredDataError {
+  script: number;
+  line: number;
+  column: number;
+  message: string;
+}
+
+export interface StructuredData {
+  entities: Entity[];
+  errors: StructuredDataError[];
+}
+
+export interface HeadingNode {
+  level: number;
+  text: string;
+  children: HeadingNode[];
+}
+
+export interface OutlineIssue {
+  type: 'no_h1' | 'multiple_h1' | 'skipped_level' | 'empty_heading' | 'hidden_heading';
+  level?: number;
+  text?: string;
+  message: string;
+}
+
+export interface HeadingOutline {
+  headings: HeadingNode[];
+  issues: OutlineIssue[];
+}
+
+export interface Finding {
+  rule: string;
+  severity: 'error' | 'warning';
+  message: string;
+  path: string;
+}
+
+export type FormKind =
+  | 'login'
+  | 'signup'
+  | 'search'
+  | 'newsletter'
+  | 'checkout'
+  | 'password_reset'
+  | 'unknown';
+
+export interface FormInput {
+  tag: string;
+  type?: string;
+  name?: string;
+  id?: string;
+  autocomplete?: string;
+  required?: boolean;
+  credential?: boolean;
+}
+
+export interface FormReport {
+  path: string;
+  method: string;
+  action: string;
+  plainHttp: boolean;
+  inputs: FormInput[];
+  hasCsrfToken: boolean;
+  kind: FormKind;
+  confidence: number;
+  signals: string[];
+}
+
+export interface LoginDetection {
+  path: string;
+  confidence: number;
+  signals: string[];
+  identifierFirst: boolean;
+  outsideForm: boolean;
+}
+
+export interface EncodingReport {
+  detected: string;
+  source: 'bom' | 'header' | 'meta' | 'default';
+  bom?: string;
+  header?: string;
+  meta?: string;
+  mismatch: boolean;
+  unsupported?: string[];
+}
+
+export interface XMLError {
+  line: number;
+  column: number;
+  message: string;
+}
+
+export interface XMLReport {
+  wellFormed: boolean;
+  errors: XMLError[];
+}
+
+export interface TransferMetrics {
+  contentEncoding?: string;
+  compressedBytes: number;
+  decompressedBytes: number;
+  ttfbMs: number;
+  downloadMs: number;
+  truncated: boolean;
+  limitBytes: number;
 }
 
 export interface AnalyzeResponse {
   htmlVersion: string;
+  encoding: EncodingReport;
+  xhtml?: XMLReport;
   title: string;
   headings: Record<string, number>;
+  outline: HeadingOutline;
   internalLinks: number;
   externalLinks: number;
   inaccessibleLinks: number;
+  uniqueInternalLinks: number;
+  uniqueExternalLinks: number;
+  uniqueInaccessibleLinks: number;
   hasLoginForm: boolean;
+  forms: FormReport[];
+  login?: LoginDetection;
+  pageRedirects?: RedirectChain;
+  transfer?: TransferMetrics;
+  scope: Scope;
+  normalize: NormalizeOptions;
+  resources: Partial<Record<ResourceKind, ResourceCounts>>;
+  links: LinkResult[];
+  fragments: FragmentReport;
+  metadata: Metadata;
+  social: SocialMetadata;
+  structuredData: StructuredData;
+  accessibility: Finding[];
 }
 
 export interface ErrorResponse {
   statusCode: number;
+  code?:
+    | 'unsupported_media_type'
+    | 'target_blocked'
+    | 'queue_full'
+    | 'job_finished'
+    | 'redirect_loop'
+    | 'too_many_redirects';
   message: string;
+  redirects?: RedirectChain;
+}
+
+export type Phase = 'fetched' | 'parsed' | 'static' | 'links';
+
+export interface Progress {
+  phase: Phase;
+  checked?: number;
+  total?: number;
+  result?: AnalyzeResponse;
 }
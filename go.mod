@@ -2,4 +2,8 @@ module github.com/moustafa/home24
 
 go 1.25.5
 
-require golang.org/x/net v0.50.0 // indirect
+require (
+	github.com/andybalholm/brotli v1.2.0
+	golang.org/x/net v0.50.0
+	golang.org/x/text v0.34.0
+)
@@ -0,0 +1,304 @@
+package analyzer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Finding severities.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Accessibility rule ids.
+const (
+	RuleImageAlt    = "image-alt"
+	RuleLabel       = "label"
+	RuleHTMLLang    = "html-lang"
+	RuleEmptyLink   = "link-name"
+	RuleEmptyButton = "button-name"
+	RuleDuplicateID = "duplicate-id"
+	RuleTabindex    = "tabindex"
+	RuleSkipLink    = "skip-link"
+)
+
+// Finding is a single accessibility problem. Path is a CSS-like selector
+// for the offending element.
+type Finding struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Path     string `json:"path"`
+}
+
+// labelExemptInputs are input types that need no associated label.
+var labelExemptInputs = map[string]bool{
+	"hidden": true,
+	"submit": true,
+	"reset":  true,
+	"button": true,
+	"image":  true,
+}
+
+// AuditAccessibility runs WCAG-oriented checks over doc and returns the
+// findings in document order, followed by document-level findings.
+func AuditAccessibility(doc *html.Node) []Finding {
+	a := auditor{
+		findings: []Finding{},
+		labelled: make(map[string]bool),
+		ids:      make(map[string]bool),
+	}
+	a.collectLabels(doc)
+	a.walk(doc)
+
+	if html := findElement(doc, "html"); html != nil {
+		if lang, _ := attr(html, "lang"); strings.TrimSpace(lang) == "" {
+			a.add(html, RuleHTMLLang, SeverityError, "<html> element has no lang attribute")
+		}
+	}
+	if body := findElement(doc, "body"); body != nil && !hasSkipLink(body) {
+		a.add(body, RuleSkipLink, SeverityWarning, "page has no skip link to its main content")
+	}
+	return a.findings
+}
+
+type auditor struct {
+	findings []Finding
+	// labelled holds the ids referenced by <label for>.
+	labelled map[string]bool
+	ids      map[string]bool
+}
+
+func (a *auditor) add(n *html.Node, rule, severity, msg string) {
+	a.findings = append(a.findings, Finding{
+		Rule:     rule,
+		Severity: severity,
+		Message:  msg,
+		Path:     cssPath(n),
+	})
+}
+
+func (a *auditor) collectLabels(n *html.Node) {
+	if n.Type == html.ElementNode && n.Data == "label" {
+		if id, ok := attr(n, "for"); ok {
+			a.labelled[id] = true
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		a.collectLabels(c)
+	}
+}
+
+func (a *auditor) walk(n *html.Node) {
+	if n.Type == html.ElementNode {
+		a.check(n)
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		a.walk(c)
+	}
+}
+
+func (a *auditor) check(n *html.Node) {
+	if id, ok := attr(n, "id"); ok && id != "" {
+		if a.ids[id] {
+			a.add(n, RuleDuplicateID, SeverityError, fmt.Sprintf("id %q is used more than once", id))
+		}
+		a.ids[id] = true
+	}
+
+	if ti, ok := attr(n, "tabindex"); ok {
+		if v, err := strconv.Atoi(strings.TrimSpace(ti)); err == nil && v > 0 {
+			a.add(n, RuleTabindex, SeverityWarning, fmt.Sprintf("positive tabindex %d disrupts the focus order", v))
+		}
+	}
+
+	if isHidden(n) {
+		return
+	}
+
+	switch n.Data {
+	case "img", "area":
+		if _, ok := attr(n, "alt"); !ok && !isPresentational(n) {
+			a.add(n, RuleImageAlt, SeverityError, fmt.Sprintf("<%s> has no alt attribute", n.Data))
+		}
+	case "input":
+		t, _ := attr(n, "type")
+		t = strings.ToLower(strings.TrimSpace(t))
+		switch {
+		case t == "image":
+			if alt, _ := attr(n, "alt"); strings.TrimSpace(alt) == "" {
+				a.add(n, RuleImageAlt, SeverityError, "image button has no alt text")
+			}
+		case t == "button":
+			if v, _ := attr(n, "value"); strings.TrimSpace(v) == "" && !hasARIAName(n) {
+				a.add(n, RuleEmptyButton, SeverityError, "button has no accessible name")
+			}
+		case !labelExemptInputs[t]:
+			a.checkLabel(n)
+		}
+	case "select", "textarea":
+		a.checkLabel(n)
+	case "a":
+		if _, ok := attr(n, "href"); ok && accessibleName(n) == "" {
+			a.add(n, RuleEmptyLink, SeverityError, "link has no accessible name")
+		}
+	case "button":
+		if accessibleName(n) == "" {
+			a.add(n, RuleEmptyButton, SeverityError, "button has no accessible name")
+		}
+	}
+}
+
+func (a *auditor) checkLabel(n *html.Node) {
+	if id, _ := attr(n, "id"); id != "" && a.labelled[id] {
+		return
+	}
+	if hasARIAName(n) {
+		return
+	}
+	if title, _ := attr(n, "title"); strings.TrimSpace(title) != "" {
+		return
+	}
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode && p.Data == "label" {
+			return
+		}
+	}
+	a.add(n, RuleLabel, SeverityError, fmt.Sprintf("<%s> has no associated label", n.Data))
+}
+
+func hasARIAName(n *html.Node) bool {
+	for _, key := range []string{"aria-label", "aria-labelledby"} {
+		if v, _ := attr(n, key); strings.TrimSpace(v) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// accessibleName approximates the accessible name of a link or button: ARIA
+// labels, its text, the alt text of contained images, or its title.
+func accessibleName(n *html.Node) string {
+	for _, key := range []string{"aria-label", "aria-labelledby"} {
+		if v, _ := attr(n, key); strings.TrimSpace(v) != "" {
+			return strings.TrimSpace(v)
+		}
+	}
+	if text := innerText(n); text != "" {
+		return text
+	}
+	if alt := imageAltText(n); alt != "" {
+		return alt
+	}
+	title, _ := attr(n, "title")
+	return strings.TrimSpace(title)
+}
+
+func imageAltText(n *html.Node) string {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		if c.Data == "img" {
+			if alt, _ := attr(c, "alt"); strings.TrimSpace(alt) != "" {
+				return strings.TrimSpace(alt)
+			}
+		}
+		if alt := imageAltText(c); alt != "" {
+			return alt
+		}
+	}
+	return ""
+}
+
+func isPresentational(n *html.Node) bool {
+	role, _ := attr(n, "role")
+	role = strings.ToLower(strings.TrimSpace(role))
+	return role == "presentation" || role == "none"
+}
+
+// isHidden reports whether n is removed from the accessibility tree by itself
+// or an ancestor.
+func isHidden(n *html.Node) bool {
+	for ; n != nil; n = n.Parent {
+		if n.Type != html.ElementNode {
+			continue
+		}
+		if v, _ := attr(n, "aria-hidden"); strings.EqualFold(strings.TrimSpace(v), "true") {
+			return true
+		}
+		if _, ok := attr(n, "hidden"); ok {
+			return true
+		}
+		if n.Data == "template" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasSkipLink reports whether the first link in body jumps to a fragment of
+// the page, which is how skip links are conventionally built.
+func hasSkipLink(body *html.Node) bool {
+	first := findElementWithAttr(body, "a", "href")
+	if first == nil {
+		return false
+	}
+	href, _ := attr(first, "href")
+	return strings.HasPrefix(strings.TrimSpace(href), "#") && len(strings.TrimSpace(href)) > 1
+}
+
+func findElementWithAttr(n *html.Node, tag, key string) *html.Node {
+	if n.Type == html.ElementNode && n.Data == tag {
+		if _, ok := attr(n, key); ok {
+			return n
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElementWithAttr(c, tag, key); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// cssPath builds a selector such as "html > body > ul:nth-of-type(2) > li"
+// for n, using #id where an element has one.
+func cssPath(n *html.Node) string {
+	var parts []string
+	for ; n != nil && n.Type == html.ElementNode; n = n.Parent {
+		seg := n.Data
+		if id, _ := attr(n, "id"); id != "" {
+			seg += "#" + id
+		} else if idx, total := typeIndex(n); total > 1 {
+			seg += fmt.Sprintf(":nth-of-type(%d)", idx)
+		}
+		parts = append(parts, seg)
+	}
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	return strings.Join(parts, " > ")
+}
+
+// typeIndex returns the 1-based position of n among its siblings of the same
+// tag, and how many such siblings there are.
+func typeIndex(n *html.Node) (int, int) {
+	if n.Parent == nil {
+		return 1, 1
+	}
+	idx, total := 0, 0
+	for c := n.Parent.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == n.Data {
+			total++
+			if c == n {
+				idx = total
+			}
+		}
+	}
+	return idx, total
+}
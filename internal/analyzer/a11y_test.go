@@ -0,0 +1,64 @@
+package analyzer
+
+import "testing"
+
+func TestAuditAccessibility(t *testing.T) {
+	doc := parseHTML(t, `<html><body>
+		<nav><a href="/">Home</a></nav>
+		<img src="/a.png">
+		<img src="/spacer.gif" alt="">
+		<img src="/deco.png" role="presentation">
+		<div aria-hidden="true"><img src="/hidden.png"></div>
+		<form>
+			<label for="email">Email</label><input id="email" type="email">
+			<label>Name <input type="text"></label>
+			<input type="text" aria-label="Search">
+			<input type="text" placeholder="Phone">
+			<input type="hidden" name="csrf">
+			<input type="submit">
+			<select id="country"></select>
+			<button><img src="/go.png" alt="Go"></button>
+			<button></button>
+		</form>
+		<p><a href="/cart"><i class="icon"></i></a></p>
+		<p><a href="/x" aria-label="Close">×</a></p>
+		<div id="dup"></div><div id="dup" tabindex="3"></div>
+		<span tabindex="0"></span>
+	</body></html>`)
+
+	got := AuditAccessibility(doc)
+
+	want := []struct{ rule, path string }{
+		{RuleImageAlt, "html > body > img:nth-of-type(1)"},
+		{RuleLabel, "html > body > form > input:nth-of-type(3)"},
+		{RuleLabel, "html > body > form > select#country"},
+		{RuleEmptyButton, "html > body > form > button:nth-of-type(2)"},
+		{RuleEmptyLink, "html > body > p:nth-of-type(1) > a"},
+		{RuleDuplicateID, "html > body > div#dup"},
+		{RuleTabindex, "html > body > div#dup"},
+		{RuleHTMLLang, "html"},
+		{RuleSkipLink, "html > body"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d findings, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		if got[i].Rule != w.rule || got[i].Path != w.path {
+			t.Errorf("findings[%d] = %s at %q, want %s at %q", i, got[i].Rule, got[i].Path, w.rule, w.path)
+		}
+	}
+	if got[6].Severity != SeverityWarning {
+		t.Errorf("tabindex severity = %q, want %q", got[6].Severity, SeverityWarning)
+	}
+}
+
+func TestAuditAccessibility_Clean(t *testing.T) {
+	doc := parseHTML(t, `<html lang="en"><body>
+		<a href="#main">Skip to content</a>
+		<main id="main"><img src="/a.png" alt="A chair"></main>
+	</body></html>`)
+
+	if got := AuditAccessibility(doc); len(got) != 0 {
+		t.Errorf("got findings %+v, want none", got)
+	}
+}
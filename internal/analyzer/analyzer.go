@@ -5,56 +5,224 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"slices"
 	"strings"
 
 	"golang.org/x/net/html"
 )
 
+// AnalyzeResponse is the result of analyzing a page. The link counts cover
+// anchors only and count every occurrence, with the Unique* fields counting
+// distinct normalized URLs; Resources breaks every reference down by kind.
 type AnalyzeResponse struct {
-	HTMLVersion       string         `json:"htmlVersion"`
-	Title             string         `json:"title"`
-	Headings          map[string]int `json:"headings"`
-	InternalLinks     int            `json:"internalLinks"`
-	ExternalLinks     int            `json:"externalLinks"`
-	InaccessibleLinks int            `json:"inaccessibleLinks"`
-	HasLoginForm      bool           `json:"hasLoginForm"`
+	HTMLVersion             string         `json:"htmlVersion"`
+	Encoding                EncodingReport `json:"encoding"`
+	XHTML                   *XMLReport     `json:"xhtml,omitempty"`
+	Title                   string         `json:"title"`
+	Headings                map[string]int `json:"headings"`
+	Outline                 HeadingOutline `json:"outline"`
+	InternalLinks           int            `json:"internalLinks"`
+	ExternalLinks           int            `json:"externalLinks"`
+	InaccessibleLinks       int            `json:"inaccessibleLinks"`
+	UniqueInternalLinks     int            `json:"uniqueInternalLinks"`
+	UniqueExternalLinks     int            `json:"uniqueExternalLinks"`
+	UniqueInaccessibleLinks int            `json:"uniqueInaccessibleLinks"`
+	HasLoginForm            bool           `json:"hasLoginForm"`
+	Forms                   []FormReport   `json:"forms"`
+	// Login is the form behind HasLoginForm, if any.
+	Login *LoginDetection `json:"login,omitempty"`
+	// PageRedirects is the redirect chain followed to fetch the page itself.
+	// Analyze leaves it empty; callers that fetch the page fill it in.
+	PageRedirects *RedirectChain `json:"pageRedirects,omitempty"`
+	// Transfer describes how the page was downloaded; like PageRedirects it
+	// is filled in by the caller.
+	Transfer      *TransferMetrics                `json:"transfer,omitempty"`
+	Scope         Scope                           `json:"scope"`
+	Normalize     NormalizeOptions                `json:"normalize"`
+	Resources     map[ResourceKind]ResourceCounts `json:"resources"`
+	Links         []LinkResult                    `json:"links"`
+	Fragments     FragmentReport                  `json:"fragments"`
+	Metadata      Metadata                        `json:"metadata"`
+	Social        SocialMetadata                  `json:"social"`
+	Structured    StructuredData                  `json:"structuredData"`
+	Accessibility []Finding                       `json:"accessibility"`
+}
+
+// ResourceCounts tallies the references of one resource kind. Total,
+// Internal, External and Inaccessible count occurrences; the Unique fields
+// count distinct normalized URLs.
+type ResourceCounts struct {
+	Total              int `json:"total"`
+	Internal           int `json:"internal"`
+	External           int `json:"external"`
+	Inaccessible       int `json:"inaccessible"`
+	Unique             int `json:"unique"`
+	UniqueInternal     int `json:"uniqueInternal"`
+	UniqueExternal     int `json:"uniqueExternal"`
+	UniqueInaccessible int `json:"uniqueInaccessible"`
 }
 
 const defaultWorkers = 10
 
-func Analyze(ctx context.Context, rawHTML []byte, pageURL string) (*AnalyzeResponse, error) {
-	doc, err := html.Parse(bytes.NewReader(rawHTML))
+// Option customizes a single call to Analyze.
+type Option func(*options)
+
+type options struct {
+	checker   *LinkChecker
+	scope     Scope
+	normalize NormalizeOptions
+	limits    *MetadataLimits
+	required  map[string][]string
+	// contentType is the Content-Type header the page was served with.
+	contentType string
+	progress    func(Progress)
+}
+
+// WithLinkChecker makes Analyze check links with c instead of a LinkChecker
+// with default settings.
+func WithLinkChecker(c *LinkChecker) Option {
+	return func(o *options) { o.checker = c }
+}
+
+// WithScope sets the policy deciding which links are internal. The default
+// is ScopeHost.
+func WithScope(s Scope) Option {
+	return func(o *options) { o.scope = s }
+}
+
+// WithNormalization sets the optional URL normalization steps used to
+// deduplicate links.
+func WithNormalization(n NormalizeOptions) Option {
+	return func(o *options) { o.normalize = n }
+}
+
+// WithMetadataLimits sets the title and description length limits. The
+// default is DefaultMetadataLimits.
+func WithMetadataLimits(l MetadataLimits) Option {
+	return func(o *options) { o.limits = &l }
+}
+
+// WithRequiredProperties sets the properties, as dotted paths, that
+// structured data entities of each schema type must have. The default is
+// DefaultRequiredProperties.
+func WithRequiredProperties(required map[string][]string) Option {
+	return func(o *options) { o.required = required }
+}
+
+// WithContentType passes the Content-Type header the page was served with,
+// whose charset parameter takes precedence over a <meta> declaration.
+func WithContentType(ct string) Option {
+	return func(o *options) { o.contentType = ct }
+}
+
+func Analyze(ctx context.Context, rawHTML []byte, pageURL string, opts ...Option) (*AnalyzeResponse, error) {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.checker == nil {
+		o.checker = NewLinkChecker()
+	}
+	if o.limits == nil {
+		limits := DefaultMetadataLimits()
+		o.limits = &limits
+	}
+	if o.required == nil {
+		o.required = DefaultRequiredProperties()
+	}
+	scope, err := o.scope.Normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	decoded, enc := DecodeHTML(rawHTML, o.contentType)
+	doc, err := html.Parse(bytes.NewReader(decoded))
 	if err != nil {
 		return nil, fmt.Errorf("parsing HTML: %w", err)
 	}
 
+	var xhtml *XMLReport
+	if isXHTMLContentType(o.contentType) {
+		report := CheckWellFormed(decoded)
+		xhtml = &report
+	}
+
 	base, err := url.Parse(pageURL)
 	if err != nil {
 		return nil, fmt.Errorf("parsing page URL %s: %w", pageURL, err)
 	}
 
-	links := ClassifyLinks(doc, base)
+	o.report(Progress{Phase: PhaseParsed})
 
-	var internal, external int
-	for _, l := range links {
-		if l.IsInternal {
-			internal++
-		} else {
-			external++
-		}
+	links := DedupeLinks(ClassifyLinksInScope(doc, base, scope), o.normalize)
+	login := DetectLogin(doc, base)
+	social := ExtractSocial(doc)
+	images := social.ImageLinks(base, DocumentBase(doc, base), scope)
+	social.Images = []LinkResult{}
+
+	resp := &AnalyzeResponse{
+		HTMLVersion:   detectHTMLVersion(doc),
+		Encoding:      enc,
+		XHTML:         xhtml,
+		Title:         extractTitle(doc),
+		Headings:      countHeadings(doc),
+		Outline:       BuildOutline(doc),
+		HasLoginForm:  login != nil,
+		Login:         login,
+		Forms:         AnalyzeForms(doc, base),
+		Scope:         scope,
+		Normalize:     o.normalize,
+		Resources:     map[ResourceKind]ResourceCounts{},
+		Links:         []LinkResult{},
+		Fragments:     CheckFragments(doc, base),
+		Metadata:      ExtractMetadata(doc, base, *o.limits),
+		Social:        social,
+		Structured:    ExtractStructuredData(doc, o.required),
+		Accessibility: AuditAccessibility(doc),
 	}
+	static := *resp
+	o.report(Progress{Phase: PhaseStatic, Result: &static})
 
-	inaccessible := CountInaccessibleLinks(ctx, links, defaultWorkers)
+	// Links and share images are checked together so that a URL used as
+	// both is only requested once.
+	results := o.checker.CheckProgress(ctx, slices.Concat(links, images), func(checked, total int) {
+		o.report(Progress{Phase: PhaseLinks, Checked: checked, Total: total})
+	})
+	resp.Links = results[:len(links)]
+	resp.Social.Images = results[len(links):]
 
-	return &AnalyzeResponse{
-		HTMLVersion:       detectHTMLVersion(doc),
-		Title:             extractTitle(doc),
-		Headings:          countHeadings(doc),
-		InternalLinks:     internal,
-		ExternalLinks:     external,
-		InaccessibleLinks: inaccessible,
-		HasLoginForm:      hasLoginForm(doc),
-	}, nil
+	resp.Resources = countResources(resp.Links)
+	anchors := resp.Resources[KindAnchor]
+	resp.InternalLinks = anchors.Internal
+	resp.ExternalLinks = anchors.External
+	resp.InaccessibleLinks = anchors.Inaccessible
+	resp.UniqueInternalLinks = anchors.UniqueInternal
+	resp.UniqueExternalLinks = anchors.UniqueExternal
+	resp.UniqueInaccessibleLinks = anchors.UniqueInaccessible
+	return resp, nil
+}
+
+func countResources(results []LinkResult) map[ResourceKind]ResourceCounts {
+	counts := make(map[ResourceKind]ResourceCounts)
+	for _, r := range results {
+		n := max(r.Occurrences, 1)
+		c := counts[r.Kind]
+		c.Total += n
+		c.Unique++
+		if r.IsInternal {
+			c.Internal += n
+			c.UniqueInternal++
+		} else {
+			c.External += n
+			c.UniqueExternal++
+		}
+		if r.Inaccessible() {
+			c.Inaccessible += n
+			c.UniqueInaccessible++
+		}
+		counts[r.Kind] = c
+	}
+	return counts
 }
 
 func detectHTMLVersion(doc *html.Node) string {
@@ -130,6 +298,22 @@ func textContent(n *html.Node) string {
 	return b.String()
 }
 
+// innerText returns the whitespace-collapsed text of n and all its descendants.
+func innerText(n *html.Node) string {
+	var parts []string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			parts = append(parts, strings.Fields(n.Data)...)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(parts, " ")
+}
+
 var headingTags = map[string]bool{
 	"h1": true, "h2": true, "h3": true,
 	"h4": true, "h5": true, "h6": true,
@@ -152,56 +336,3 @@ func countHeadingsRecursive(n *html.Node, counts map[string]int) {
 		countHeadingsRecursive(c, counts)
 	}
 }
-
-func hasLoginForm(doc *html.Node) bool {
-	return findLoginForm(doc)
-}
-
-func findLoginForm(n *html.Node) bool {
-	if n.Type == html.ElementNode && n.Data == "form" {
-		if formIsLogin(n) {
-			return true
-		}
-	}
-	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		if findLoginForm(c) {
-			return true
-		}
-	}
-	return false
-}
-
-func formIsLogin(form *html.Node) bool {
-	return findLoginInput(form)
-}
-
-func findLoginInput(n *html.Node) bool {
-	if n.Type == html.ElementNode && n.Data == "input" {
-		if isLoginInput(n) {
-			return true
-		}
-	}
-	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		if findLoginInput(c) {
-			return true
-		}
-	}
-	return false
-}
-
-func isLoginInput(n *html.Node) bool {
-	for _, a := range n.Attr {
-		switch a.Key {
-		case "type":
-			if strings.EqualFold(a.Val, "password") {
-				return true
-			}
-		case "name", "id":
-			lower := strings.ToLower(a.Val)
-			if strings.Contains(lower, "password") || strings.Contains(lower, "login") {
-				return true
-			}
-		}
-	}
-	return false
-}
@@ -2,7 +2,12 @@ package analyzer
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
 	"strings"
+	"sync/atomic"
 	"testing"
 
 	"golang.org/x/net/html"
@@ -201,9 +206,9 @@ func TestHasLoginForm(t *testing.T) {
 			want: true,
 		},
 		{
-			name: "form with name containing login",
+			name: "lone field with name containing login",
 			html: `<html><body><form><input type="text" name="login_field"></form></body></html>`,
-			want: true,
+			want: false,
 		},
 		{
 			name: "form without login/password inputs",
@@ -220,10 +225,133 @@ func TestHasLoginForm(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			doc := parseHTML(t, tt.html)
-			got := hasLoginForm(doc)
+			got := DetectLogin(doc, &url.URL{Scheme: "https", Host: "example.com", Path: "/"}) != nil
 			if got != tt.want {
-				t.Errorf("hasLoginForm() = %v, want %v", got, tt.want)
+				t.Errorf("DetectLogin() found = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestAnalyze_ResourceCounts(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.png" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	rawHTML := []byte(`<html><body>
+		<a href="/about">About</a>
+		<a href="https://other.invalid/">Other</a>
+		<img src="/logo.png"><img src="/missing.png">
+	</body></html>`)
+	c := NewLinkChecker()
+	c.Retry.MaxAttempts = 1
+	resp, err := Analyze(context.Background(), rawHTML, ts.URL, WithLinkChecker(c))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.InternalLinks != 1 || resp.ExternalLinks != 1 || resp.InaccessibleLinks != 1 {
+		t.Errorf("anchor counts = %d internal, %d external, %d inaccessible, want 1, 1, 1",
+			resp.InternalLinks, resp.ExternalLinks, resp.InaccessibleLinks)
+	}
+	want := ResourceCounts{
+		Total: 2, Internal: 2, Inaccessible: 1,
+		Unique: 2, UniqueInternal: 2, UniqueInaccessible: 1,
+	}
+	if got := resp.Resources[KindImage]; got != want {
+		t.Errorf("Resources[image] = %+v, want %+v", got, want)
+	}
+	if len(resp.Links) != 4 {
+		t.Errorf("got %d link results, want 4", len(resp.Links))
+	}
+}
+
+func TestAnalyze_DuplicateLinks(t *testing.T) {
+	var hits atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/cart" {
+			hits.Add(1)
+		}
+	}))
+	defer ts.Close()
+
+	rawHTML := []byte(`<html><body>
+		<header><a href="/cart">Cart</a></header>
+		<main><a href="/cart#items"></a><a href="/cart"><img src="/cart"></a></main>
+		<footer><a href="/cart?">Cart</a><a href="/about">About</a></footer>
+	</body></html>`)
+	resp, err := Analyze(context.Background(), rawHTML, ts.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.InternalLinks != 5 || resp.UniqueInternalLinks != 2 {
+		t.Errorf("internal links = %d total, %d unique, want 5, 2", resp.InternalLinks, resp.UniqueInternalLinks)
+	}
+	if resp.Links[0].Occurrences != 4 {
+		t.Errorf("Links[0].Occurrences = %d, want 4", resp.Links[0].Occurrences)
+	}
+	if got := hits.Load(); got != 1 {
+		t.Errorf("server saw %d requests for /cart, want 1", got)
+	}
+}
+
+func TestAnalyze_NormalizationKeepsLinkURL(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/a/" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	rawHTML := []byte(`<html><body><a href="/a/?y=2&x=1">A</a><a href="/a?x=1&y=2">Again</a></body></html>`)
+	resp, err := Analyze(context.Background(), rawHTML, ts.URL,
+		WithNormalization(NormalizeOptions{StripTrailingSlash: true, SortQuery: true}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(resp.Links) != 1 {
+		t.Fatalf("got %d links, want the two references deduplicated: %+v", len(resp.Links), resp.Links)
+	}
+	l := resp.Links[0]
+	if want := ts.URL + "/a/?y=2&x=1"; l.URL != want || !l.Accessible || l.Occurrences != 2 {
+		t.Errorf("link = %+v, want %s accessible with 2 occurrences", l, want)
+	}
+}
+
+func TestAnalyze_Progress(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	rawHTML := []byte(`<html><head><title>Progress</title>
+		<meta property="og:image" content="/a"></head><body>
+		<a href="/a">A</a><a href="/b">B</a><img src="/c">
+	</body></html>`)
+	var got []Progress
+	_, err := Analyze(context.Background(), rawHTML, ts.URL, WithProgress(func(p Progress) {
+		got = append(got, p)
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var phases []Phase
+	for _, p := range got {
+		phases = append(phases, p.Phase)
+	}
+	want := []Phase{PhaseParsed, PhaseStatic, PhaseLinks, PhaseLinks, PhaseLinks}
+	if !slices.Equal(phases, want) {
+		t.Fatalf("phases = %v, want %v", phases, want)
+	}
+	if r := got[1].Result; r == nil || r.Title != "Progress" || len(r.Links) != 0 {
+		t.Errorf("static result = %+v, want title and no links", r)
+	}
+	// The share image /a is the anchor /a, so it is checked once.
+	if last := got[len(got)-1]; last.Checked != 3 || last.Total != 3 {
+		t.Errorf("last progress = %d/%d, want 3/3", last.Checked, last.Total)
+	}
+}
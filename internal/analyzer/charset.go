@@ -0,0 +1,164 @@
+package analyzer
+
+import (
+	"bytes"
+	"mime"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Encoding sources, in order of precedence.
+const (
+	EncodingFromBOM     = "bom"
+	EncodingFromHeader  = "header"
+	EncodingFromMeta    = "meta"
+	EncodingFromDefault = "default"
+)
+
+// metaPrescanBytes is how far into the document a <meta> charset
+// declaration is looked for, as in the HTML encoding sniffing algorithm.
+const metaPrescanBytes = 1024
+
+// EncodingReport describes how a document's bytes were decoded. Encoding
+// names are the canonical WHATWG names, e.g. "windows-1252" for a declared
+// "iso-8859-1".
+type EncodingReport struct {
+	// Detected is the encoding the document was decoded with and Source
+	// where it came from.
+	Detected string `json:"detected"`
+	Source   string `json:"source"`
+	BOM      string `json:"bom,omitempty"`
+	// Header and Meta are the charsets declared in the Content-Type header
+	// and in a <meta> element, as written.
+	Header string `json:"header,omitempty"`
+	Meta   string `json:"meta,omitempty"`
+	// Mismatch is set when the BOM, header and meta declarations name
+	// different encodings.
+	Mismatch bool `json:"mismatch"`
+	// Unsupported lists declared charsets that are not known encodings.
+	Unsupported []string `json:"unsupported,omitempty"`
+}
+
+var boms = []struct {
+	bom  []byte
+	name string
+}{
+	{[]byte{0xEF, 0xBB, 0xBF}, "utf-8"},
+	{[]byte{0xFE, 0xFF}, "utf-16be"},
+	{[]byte{0xFF, 0xFE}, "utf-16le"},
+}
+
+// DecodeHTML transcodes raw to UTF-8. The encoding is taken from a byte
+// order mark, then the charset parameter of contentType, then a <meta>
+// declaration in the first 1024 bytes; without any, raw is assumed to be
+// UTF-8 if valid and windows-1252 otherwise.
+func DecodeHTML(raw []byte, contentType string) ([]byte, EncodingReport) {
+	var r EncodingReport
+	var enc encoding.Encoding
+
+	body := raw
+	for _, b := range boms {
+		if bytes.HasPrefix(raw, b.bom) {
+			r.BOM = b.name
+			body = raw[len(b.bom):]
+			break
+		}
+	}
+
+	var declared []string
+	if r.BOM != "" {
+		enc, r.Detected = charset.Lookup(r.BOM)
+		r.Source = EncodingFromBOM
+		declared = append(declared, r.Detected)
+	}
+
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		r.Header = params["charset"]
+	}
+	r.Meta = metaCharset(body)
+
+	for _, d := range []struct{ label, source string }{
+		{r.Header, EncodingFromHeader},
+		{r.Meta, EncodingFromMeta},
+	} {
+		if d.label == "" {
+			continue
+		}
+		e, name := charset.Lookup(d.label)
+		if e == nil {
+			r.Unsupported = append(r.Unsupported, d.label)
+			continue
+		}
+		// A <meta> claiming UTF-16 cannot be right, since it was readable
+		// as ASCII; the HTML standard treats it as UTF-8.
+		if d.source == EncodingFromMeta && strings.HasPrefix(name, "utf-16") {
+			e, name = encoding.Nop, "utf-8"
+		}
+		declared = append(declared, name)
+		if enc == nil {
+			enc, r.Detected, r.Source = e, name, d.source
+		}
+	}
+
+	for _, name := range declared {
+		if name != declared[0] {
+			r.Mismatch = true
+		}
+	}
+
+	if enc == nil {
+		r.Source = EncodingFromDefault
+		if utf8.Valid(body) {
+			enc, r.Detected = encoding.Nop, "utf-8"
+		} else {
+			enc, r.Detected = charmap.Windows1252, "windows-1252"
+		}
+	}
+
+	if r.Detected == "utf-8" {
+		return body, r
+	}
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return body, r
+	}
+	return decoded, r
+}
+
+// metaCharset returns the charset declared by the first <meta charset> or
+// <meta http-equiv="content-type"> within the first metaPrescanBytes of raw.
+func metaCharset(raw []byte) string {
+	z := html.NewTokenizer(bytes.NewReader(raw[:min(len(raw), metaPrescanBytes)]))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			if tok.Data != "meta" {
+				continue
+			}
+			var httpEquiv, content string
+			for _, a := range tok.Attr {
+				switch a.Key {
+				case "charset":
+					return strings.TrimSpace(a.Val)
+				case "http-equiv":
+					httpEquiv = a.Val
+				case "content":
+					content = a.Val
+				}
+			}
+			if strings.EqualFold(strings.TrimSpace(httpEquiv), "content-type") {
+				if _, params, err := mime.ParseMediaType(content); err == nil && params["charset"] != "" {
+					return params["charset"]
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeHTML(t *testing.T) {
+	tests := []struct {
+		name        string
+		raw         string
+		contentType string
+		wantText    string
+		want        EncodingReport
+	}{
+		{
+			name:     "plain utf-8",
+			raw:      "<p>Café</p>",
+			wantText: "<p>Café</p>",
+			want:     EncodingReport{Detected: "utf-8", Source: EncodingFromDefault},
+		},
+		{
+			name:     "undeclared latin-1",
+			raw:      "<p>Caf\xe9</p>",
+			wantText: "<p>Café</p>",
+			want:     EncodingReport{Detected: "windows-1252", Source: EncodingFromDefault},
+		},
+		{
+			name:        "header charset",
+			raw:         "<p>Caf\xe9</p>",
+			contentType: "text/html; charset=iso-8859-1",
+			wantText:    "<p>Café</p>",
+			want:        EncodingReport{Detected: "windows-1252", Source: EncodingFromHeader, Header: "iso-8859-1"},
+		},
+		{
+			name:     "meta charset",
+			raw:      "<meta charset=\"shift_jis\"><p>\x93\xfa\x96\x7b</p>",
+			wantText: "<meta charset=\"shift_jis\"><p>日本</p>",
+			want:     EncodingReport{Detected: "shift_jis", Source: EncodingFromMeta, Meta: "shift_jis"},
+		},
+		{
+			name:     "meta http-equiv",
+			raw:      `<meta http-equiv="Content-Type" content="text/html; charset=windows-1251"><p>` + "\xcf\xf0\xe8\xe2\xe5\xf2</p>",
+			wantText: `<meta http-equiv="Content-Type" content="text/html; charset=windows-1251"><p>Привет</p>`,
+			want:     EncodingReport{Detected: "windows-1251", Source: EncodingFromMeta, Meta: "windows-1251"},
+		},
+		{
+			name:        "bom beats header and meta",
+			raw:         "\xef\xbb\xbf<meta charset=\"latin1\"><p>Café</p>",
+			contentType: "text/html; charset=iso-8859-1",
+			wantText:    "<meta charset=\"latin1\"><p>Café</p>",
+			want: EncodingReport{
+				Detected: "utf-8", Source: EncodingFromBOM, BOM: "utf-8",
+				Header: "iso-8859-1", Meta: "latin1", Mismatch: true,
+			},
+		},
+		{
+			name:        "header and meta agree under different labels",
+			raw:         "<meta charset=\"latin1\"><p>Caf\xe9</p>",
+			contentType: "text/html; charset=ISO-8859-1",
+			wantText:    "<meta charset=\"latin1\"><p>Café</p>",
+			want:        EncodingReport{Detected: "windows-1252", Source: EncodingFromHeader, Header: "ISO-8859-1", Meta: "latin1"},
+		},
+		{
+			name:        "unknown header charset falls back to meta",
+			raw:         "<meta charset=\"utf-8\"><p>Café</p>",
+			contentType: "text/html; charset=klingon",
+			wantText:    "<meta charset=\"utf-8\"><p>Café</p>",
+			want: EncodingReport{
+				Detected: "utf-8", Source: EncodingFromMeta, Header: "klingon", Meta: "utf-8",
+				Unsupported: []string{"klingon"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, report := DecodeHTML([]byte(tt.raw), tt.contentType)
+
+			if string(got) != tt.wantText {
+				t.Errorf("decoded = %q, want %q", got, tt.wantText)
+			}
+			if !reflect.DeepEqual(report, tt.want) {
+				t.Errorf("report = %+v, want %+v", report, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,603 @@
+package analyzer
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/moustafa/home24/internal/netguard"
+)
+
+var errInvalidURL = errors.New("invalid URL")
+
+// headRejectedStatuses are responses to HEAD that usually mean the server
+// does not support the method rather than that the resource is missing.
+var headRejectedStatuses = map[int]bool{
+	http.StatusForbidden:        true,
+	http.StatusMethodNotAllowed: true,
+	http.StatusNotImplemented:   true,
+}
+
+// maxDrainBytes bounds how much of a GET fallback body is read before the
+// connection is released.
+const maxDrainBytes = 64 << 10
+
+const (
+	defaultPerHost       = 4
+	defaultMaxRetryAfter = 10 * time.Second
+
+	// maxHostPause bounds how long a Retry-After holds back a host.
+	maxHostPause = 30 * time.Second
+)
+
+var linkClient = &http.Client{
+	Timeout:       5 * time.Second,
+	CheckRedirect: CheckRedirect,
+}
+
+// ErrorClass names the kind of failure that made a link inaccessible.
+type ErrorClass string
+
+const (
+	ErrorNone       ErrorClass = ""
+	ErrorStatus     ErrorClass = "http_status"
+	ErrorDNS        ErrorClass = "dns"
+	ErrorTimeout    ErrorClass = "timeout"
+	ErrorTLS        ErrorClass = "tls"
+	ErrorRefused    ErrorClass = "refused"
+	ErrorReset      ErrorClass = "reset"
+	ErrorRedirects  ErrorClass = "too_many_redirects"
+	ErrorLoop       ErrorClass = "redirect_loop"
+	ErrorInvalidURL ErrorClass = "invalid_url"
+	ErrorCanceled   ErrorClass = "canceled"
+	ErrorBlocked    ErrorClass = "blocked"
+	// ErrorRateLimited marks a link still answered with 429 after the
+	// retries. The link was not found broken, so it is not counted as
+	// inaccessible.
+	ErrorRateLimited ErrorClass = "rate_limited"
+	ErrorOther       ErrorClass = "other"
+)
+
+// LinkResult is the outcome of checking a single link.
+type LinkResult struct {
+	URL           string         `json:"url"`
+	Text          string         `json:"text"`
+	Kind          ResourceKind   `json:"kind"`
+	IsInternal    bool           `json:"isInternal"`
+	Accessible    bool           `json:"accessible"`
+	Method        string         `json:"method,omitempty"`
+	StatusCode    int            `json:"statusCode,omitempty"`
+	Error         ErrorClass     `json:"error,omitempty"`
+	Occurrences   int            `json:"occurrences"`
+	Redirects     int            `json:"redirects"`
+	RedirectChain *RedirectChain `json:"redirectChain,omitempty"`
+	Attempts      int            `json:"attempts"`
+	LatencyMS     int64          `json:"latencyMs"`
+}
+
+// RetryPolicy decides whether a failed link check is retried and how long to
+// back off between attempts.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts per link, including the
+	// first. Values below 2 disable retries.
+	MaxAttempts int
+	// BaseDelay is the backoff before the first retry; it doubles on each
+	// further retry up to MaxDelay and is jittered.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// RetryStatuses lists the response status codes worth retrying.
+	RetryStatuses []int
+	// RetryErrors lists the transport failures worth retrying.
+	RetryErrors []ErrorClass
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   200 * time.Millisecond,
+		MaxDelay:    2 * time.Second,
+		RetryStatuses: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+		RetryErrors: []ErrorClass{ErrorTimeout, ErrorReset},
+	}
+}
+
+func (p RetryPolicy) retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return slices.Contains(p.RetryErrors, classifyError(err))
+	}
+	return slices.Contains(p.RetryStatuses, resp.StatusCode)
+}
+
+// backoff returns the jittered delay before the given retry (1 for the first).
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	d := p.BaseDelay << (retry - 1)
+	if d <= 0 || (p.MaxDelay > 0 && d > p.MaxDelay) {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// LinkChecker checks links for accessibility while limiting the load it puts
+// on any single host.
+type LinkChecker struct {
+	// Client sends the check requests. Redirect chains are only recorded
+	// when its CheckRedirect is CheckRedirect.
+	Client *http.Client
+
+	// Workers caps the number of links checked concurrently overall.
+	Workers int
+	// PerHost caps the number of links checked concurrently per host, across
+	// all checks made with the checker and its copies.
+	PerHost int
+	// HostDelay is the minimum time between the start of two requests to
+	// the same host.
+	HostDelay time.Duration
+	// MaxRetryAfter is the longest Retry-After on a 429 or 503 response
+	// that is waited out before retrying. Longer waits end the check, and so
+	// does any Retry-After when zero. Responses without Retry-After are
+	// retried as Retry decides.
+	MaxRetryAfter time.Duration
+	// Retry decides which failures are retried.
+	Retry RetryPolicy
+	// LongRedirectChain is the number of hops above which a link's redirect
+	// chain is flagged as too long. DefaultLongRedirectChain is used when
+	// zero.
+	LongRedirectChain int
+
+	// hosts holds the gates of every host checked with the checker. It is
+	// nil on a LinkChecker not made by NewLinkChecker, whose host limits
+	// then only apply within one call to Check.
+	hosts *hostPool
+	// shared is set on checkers returned by Shared.
+	shared *sharedChecks
+}
+
+// NewLinkChecker returns a LinkChecker with default settings. PerHost and
+// HostDelay may be changed until the first check.
+func NewLinkChecker() *LinkChecker {
+	return &LinkChecker{
+		Client:        linkClient,
+		Workers:       defaultWorkers,
+		PerHost:       defaultPerHost,
+		MaxRetryAfter: defaultMaxRetryAfter,
+		Retry:         DefaultRetryPolicy(),
+
+		LongRedirectChain: DefaultLongRedirectChain,
+
+		hosts: newHostPool(),
+	}
+}
+
+// Check checks every link and returns one result per link, in the same order
+// as links. Links sharing a URL are only requested once.
+func (c *LinkChecker) Check(ctx context.Context, links []Link) []LinkResult {
+	return c.CheckProgress(ctx, links, nil)
+}
+
+// CheckProgress is Check, calling progress after each distinct URL has been
+// checked with the number checked so far and the number to check. Calls to
+// progress are never concurrent.
+func (c *LinkChecker) CheckProgress(ctx context.Context, links []Link, progress func(checked, total int)) []LinkResult {
+	results := make([]LinkResult, len(links))
+	if len(links) == 0 {
+		return results
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, max(c.Workers, 1))
+	if c.shared != nil {
+		sem = c.shared.sem
+	}
+	hosts := c.hosts
+	if hosts == nil {
+		hosts = newHostPool()
+	}
+	first := make(map[string]int)
+	dups := make(map[int]int)
+	for i, l := range links {
+		if j, ok := first[l.URL]; ok {
+			dups[i] = j
+			continue
+		}
+		first[l.URL] = i
+	}
+
+	var mu sync.Mutex
+	checked := 0
+	done := func() {
+		if progress == nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		checked++
+		progress(checked, len(first))
+	}
+
+	for i, l := range links {
+		if _, ok := dups[i]; ok {
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, l Link) {
+			defer wg.Done()
+			defer done()
+
+			if c.shared != nil {
+				e, owner := c.shared.claim(l.URL)
+				for !owner {
+					r, ok := c.shared.wait(ctx, e, l)
+					if ok {
+						results[i] = r
+						return
+					}
+					// The owner was canceled; check the URL here instead.
+					e, owner = c.shared.claim(l.URL)
+				}
+				defer func() { c.shared.fill(l.URL, e, results[i]) }()
+			}
+
+			host := hostOf(l.URL)
+			gate := hosts.get(host, c.PerHost, c.HostDelay)
+			defer hosts.put(host, gate)
+			results[i] = c.checkLink(ctx, gate, sem, l)
+		}(i, l)
+	}
+
+	wg.Wait()
+	for i, j := range dups {
+		results[i] = results[j].forLink(links[i])
+	}
+	return results
+}
+
+// forLink returns a copy of r describing l, which shares r's URL. A
+// response status is judged again for l's kind.
+func (r LinkResult) forLink(l Link) LinkResult {
+	r.Text = l.Text
+	r.Kind = l.Kind
+	r.IsInternal = l.IsInternal
+	r.Occurrences = l.Occurrences
+	if r.StatusCode != 0 {
+		r.Accessible = accessible(l.Kind, r.Method, r.StatusCode)
+		switch {
+		case r.Accessible:
+			r.Error = ErrorNone
+		case r.StatusCode == http.StatusTooManyRequests:
+			r.Error = ErrorRateLimited
+		default:
+			r.Error = ErrorStatus
+		}
+	}
+	return r
+}
+
+// Inaccessible reports whether the check found r broken. Rate-limited links
+// are neither accessible nor inaccessible.
+func (r LinkResult) Inaccessible() bool {
+	return !r.Accessible && r.Error != ErrorRateLimited
+}
+
+// accessible reports whether a response with status to method shows that a
+// reference of the given kind works.
+func accessible(kind ResourceKind, method string, status int) bool {
+	if kind == KindForm {
+		// Form actions often accept only POST and answer a probe with 405
+		// or another error; only a missing resource means a broken form.
+		return status != http.StatusNotFound && status != http.StatusGone
+	}
+	// A ranged GET on an empty resource yields 416, which still proves the
+	// resource exists.
+	return status < 400 ||
+		(method == http.MethodGet && status == http.StatusRequestedRangeNotSatisfiable)
+}
+
+// checkLink checks l, holding a slot of gate and of sem during each attempt
+// but not while it waits to retry.
+func (c *LinkChecker) checkLink(ctx context.Context, gate *hostGate, sem chan struct{}, l Link) LinkResult {
+	result := LinkResult{URL: l.URL}.forLink(l)
+	ctx, trace := TraceRedirects(ctx)
+
+	var (
+		resp   *http.Response
+		method string
+		err    error
+	)
+	for attempt := 1; ; attempt++ {
+		// Take the host slot first so links waiting on a busy host do not
+		// hold global slots other hosts could use.
+		gate.acquire()
+		sem <- struct{}{}
+		start := time.Now()
+		resp, method, err = c.probe(ctx, gate, l.URL)
+		result.LatencyMS = time.Since(start).Milliseconds()
+		result.Attempts = attempt
+		<-sem
+		gate.release()
+
+		var wait time.Duration
+		hasWait := false
+		if err == nil {
+			wait, hasWait = retryAfter(resp, time.Now())
+		}
+		if hasWait {
+			// Hold back the other links to the host whether or not this one
+			// is retried, so they do not trip the server's limit again.
+			gate.pause(min(wait, maxHostPause))
+		}
+
+		if attempt >= c.Retry.MaxAttempts || !c.Retry.retryable(resp, err) {
+			break
+		}
+
+		delay := c.Retry.backoff(attempt)
+		if hasWait {
+			if c.MaxRetryAfter <= 0 || wait > c.MaxRetryAfter {
+				break
+			}
+			delay = max(delay, wait)
+		}
+		if sleep(ctx, delay) != nil {
+			break
+		}
+	}
+
+	result.Method = method
+	if chain := trace.Chain(c.LongRedirectChain); len(chain.Hops) > 0 || chain.Loop {
+		result.RedirectChain = &chain
+		result.Redirects = len(chain.Hops)
+	}
+	if err != nil {
+		result.Error = classifyError(err)
+		return result
+	}
+
+	result.StatusCode = resp.StatusCode
+	result.Redirects = countRedirects(resp)
+	return result.forLink(l)
+}
+
+// probe issues a HEAD request and falls back to a ranged GET when the server
+// rejects HEAD. It reports the method that produced the returned response.
+func (c *LinkChecker) probe(ctx context.Context, gate *hostGate, rawURL string) (*http.Response, string, error) {
+	resp, err := c.send(ctx, gate, http.MethodHead, rawURL)
+	if err != nil || !headRejectedStatuses[resp.StatusCode] {
+		return resp, http.MethodHead, err
+	}
+
+	resp, err = c.send(ctx, gate, http.MethodGet, rawURL)
+	return resp, http.MethodGet, err
+}
+
+func (c *LinkChecker) send(ctx context.Context, gate *hostGate, method, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidURL, err)
+	}
+	if method == http.MethodGet {
+		req.Header.Set("Range", "bytes=0-0")
+	}
+
+	if err := gate.wait(ctx); err != nil {
+		return nil, err
+	}
+	if t := redirectTraceFrom(ctx); t != nil {
+		t.reset()
+	}
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainBytes))
+	resp.Body.Close()
+
+	return resp, nil
+}
+
+// retryAfter reports how long a 429 or 503 response asks the client to wait.
+func retryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+
+	v := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+func countRedirects(resp *http.Response) int {
+	var hops int
+	for r := resp.Request; r != nil && r.Response != nil; r = r.Response.Request {
+		hops++
+	}
+	return hops
+}
+
+func classifyError(err error) ErrorClass {
+	var (
+		dnsErr       *net.DNSError
+		certErr      *tls.CertificateVerificationError
+		recordErr    tls.RecordHeaderError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+		netErr       net.Error
+		blockedErr   *netguard.BlockedError
+	)
+
+	switch {
+	case errors.Is(err, errInvalidURL):
+		return ErrorInvalidURL
+	case errors.Is(err, context.Canceled):
+		return ErrorCanceled
+	case errors.Is(err, ErrTooManyRedirects):
+		return ErrorRedirects
+	case errors.Is(err, ErrRedirectLoop):
+		return ErrorLoop
+	case errors.As(err, &blockedErr):
+		return ErrorBlocked
+	case errors.As(err, &dnsErr):
+		return ErrorDNS
+	case errors.As(err, &certErr), errors.As(err, &recordErr),
+		errors.As(err, &authorityErr), errors.As(err, &hostnameErr),
+		errors.As(err, &invalidErr):
+		return ErrorTLS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrorRefused
+	case errors.Is(err, syscall.ECONNRESET):
+		return ErrorReset
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorTimeout
+	default:
+		return ErrorOther
+	}
+}
+
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Host)
+}
+
+// hostPool hands out one hostGate per host. A gate nobody uses is dropped
+// once its host is no longer paused, so a long-lived checker does not keep
+// one for every host it has ever seen.
+type hostPool struct {
+	mu    sync.Mutex
+	gates map[string]*hostGate
+}
+
+func newHostPool() *hostPool {
+	return &hostPool{gates: make(map[string]*hostGate)}
+}
+
+// get returns the gate of host, creating it with the given limits if the
+// host has none yet. Every get must be paired with a put.
+func (p *hostPool) get(host string, perHost int, delay time.Duration) *hostGate {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	g, ok := p.gates[host]
+	if !ok {
+		// Paused gates outlive their users; sweep them as hosts are added.
+		now := time.Now()
+		for h, idle := range p.gates {
+			if idle.idle(now) {
+				delete(p.gates, h)
+			}
+		}
+		g = &hostGate{sem: make(chan struct{}, max(perHost, 1)), delay: delay}
+		p.gates[host] = g
+	}
+	g.users++
+	return g
+}
+
+// put gives back a gate returned by get, dropping it if it is idle.
+func (p *hostPool) put(host string, g *hostGate) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	g.users--
+	if g.idle(time.Now()) && p.gates[host] == g {
+		delete(p.gates, host)
+	}
+}
+
+// hostGate limits concurrency and request spacing for a single host.
+type hostGate struct {
+	sem   chan struct{}
+	delay time.Duration
+	users int // guarded by hostPool.mu
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+func (g *hostGate) acquire() { g.sem <- struct{}{} }
+func (g *hostGate) release() { <-g.sem }
+
+// wait blocks until the host may receive its next request and reserves that
+// slot for the caller.
+func (g *hostGate) wait(ctx context.Context) error {
+	g.mu.Lock()
+	now := time.Now()
+	start := now
+	if g.next.After(now) {
+		start = g.next
+	}
+	g.next = start.Add(g.delay)
+	g.mu.Unlock()
+
+	return sleep(ctx, start.Sub(now))
+}
+
+// idle reports whether dropping g loses nothing: no link is using it and
+// its host may be requested right away.
+func (g *hostGate) idle(now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.users == 0 && !g.next.After(now)
+}
+
+// pause holds back every request to the host for d.
+func (g *hostGate) pause(d time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if until := time.Now().Add(d); until.After(g.next) {
+		g.next = until
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,419 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLinkChecker_PerHostLimit(t *testing.T) {
+	var inflight, peak atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inflight.Add(1)
+		defer inflight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer ts.Close()
+
+	links := make([]Link, 12)
+	for i := range links {
+		links[i] = Link{URL: fmt.Sprintf("%s/page/%d", ts.URL, i)}
+	}
+
+	c := NewLinkChecker()
+	c.Workers = 10
+	c.PerHost = 2
+	results := c.Check(context.Background(), links)
+
+	for i, r := range results {
+		if !r.Accessible {
+			t.Errorf("results[%d] not accessible: %+v", i, r)
+		}
+	}
+	if got := peak.Load(); got > 2 {
+		t.Errorf("peak concurrent requests to host = %d, want <= 2", got)
+	}
+}
+
+func TestLinkChecker_PerHostAcrossChecks(t *testing.T) {
+	var inflight, peak atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inflight.Add(1)
+		defer inflight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer ts.Close()
+
+	c := NewLinkChecker()
+	c.Workers = 10
+	c.PerHost = 2
+	// Copies, such as the one a handler makes to set the client, share the
+	// host limits.
+	copied := *c
+	checkers := []*LinkChecker{c, &copied, c.Shared()}
+
+	var wg sync.WaitGroup
+	for p, checker := range checkers {
+		links := make([]Link, 6)
+		for i := range links {
+			links[i] = Link{URL: fmt.Sprintf("%s/page/%d/%d", ts.URL, p, i)}
+		}
+		wg.Go(func() { checker.Check(context.Background(), links) })
+	}
+	wg.Wait()
+
+	if got := peak.Load(); got > 2 {
+		t.Errorf("peak concurrent requests to host = %d, want <= 2 across checks", got)
+	}
+}
+
+func TestLinkChecker_HostDelay(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		starts []time.Time
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		starts = append(starts, time.Now())
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	links := make([]Link, 4)
+	for i := range links {
+		links[i] = Link{URL: fmt.Sprintf("%s/page/%d", ts.URL, i)}
+	}
+
+	const delay = 30 * time.Millisecond
+	c := NewLinkChecker()
+	c.PerHost = 4
+	c.HostDelay = delay
+	c.Check(context.Background(), links)
+
+	if len(starts) != len(links) {
+		t.Fatalf("server saw %d requests, want %d", len(starts), len(links))
+	}
+	if total := starts[len(starts)-1].Sub(starts[0]); total < 3*delay-5*time.Millisecond {
+		t.Errorf("requests spread over %v, want at least %v", total, 3*delay)
+	}
+}
+
+func TestLinkChecker_FormAction(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/session" && r.Method != http.MethodPost:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		case r.URL.Path == "/gone":
+			w.WriteHeader(http.StatusGone)
+		}
+	}))
+	defer ts.Close()
+
+	results := NewLinkChecker().Check(context.Background(), []Link{
+		{URL: ts.URL + "/session", Kind: KindForm},
+		{URL: ts.URL + "/gone", Kind: KindForm},
+		{URL: ts.URL + "/session", Kind: KindAnchor},
+	})
+
+	if r := results[0]; !r.Accessible || r.StatusCode != http.StatusMethodNotAllowed || r.Error != ErrorNone {
+		t.Errorf("POST-only form action = %+v, want accessible with status 405", r)
+	}
+	if r := results[1]; r.Accessible || r.Error != ErrorStatus {
+		t.Errorf("gone form action = %+v, want inaccessible", r)
+	}
+	if r := results[2]; r.Accessible || r.Error != ErrorStatus {
+		t.Errorf("anchor to a POST-only URL = %+v, want inaccessible", r)
+	}
+}
+
+func TestLinkChecker_RetryAfter(t *testing.T) {
+	var hits atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/limited":
+			if hits.Add(1) == 1 {
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		case "/too-long":
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/now":
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	c := NewLinkChecker()
+	c.MaxRetryAfter = 2 * time.Second
+
+	start := time.Now()
+	results := c.Check(context.Background(), []Link{{URL: ts.URL + "/limited"}})
+	if !results[0].Accessible {
+		t.Errorf("/limited not accessible after Retry-After: %+v", results[0])
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("check finished after %v, want it to wait out Retry-After of 1s", elapsed)
+	}
+
+	results = c.Check(context.Background(), []Link{{URL: ts.URL + "/too-long"}})
+	if results[0].Accessible || results[0].StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("/too-long = %+v, want inaccessible with status 503", results[0])
+	}
+
+	// /too-long paused the host; a fresh checker is not held back by it.
+	c = NewLinkChecker()
+	c.MaxRetryAfter = 0
+	results = c.Check(context.Background(), []Link{{URL: ts.URL + "/now"}})
+	if results[0].Attempts != 1 {
+		t.Errorf("/now took %d attempts with MaxRetryAfter 0, want 1", results[0].Attempts)
+	}
+}
+
+func TestLinkChecker_RetryAfterPausesHost(t *testing.T) {
+	var hits atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer ts.Close()
+
+	// With a single attempt the link is not retried, but the host must
+	// still be held back for the next link.
+	c := NewLinkChecker()
+	c.Retry.MaxAttempts = 1
+
+	results := c.Check(context.Background(), []Link{{URL: ts.URL + "/a"}})
+	if r := results[0]; r.Accessible || r.Error != ErrorRateLimited || r.Inaccessible() {
+		t.Errorf("429 result = %+v, want rate_limited and not inaccessible", r)
+	}
+
+	start := time.Now()
+	results = c.Check(context.Background(), []Link{{URL: ts.URL + "/b"}})
+	if !results[0].Accessible {
+		t.Errorf("/b = %+v, want accessible", results[0])
+	}
+	if elapsed := time.Since(start); elapsed < 900*time.Millisecond {
+		t.Errorf("next request to the host after %v, want it held back by Retry-After of 1s", elapsed)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		status int
+		header string
+		want   time.Duration
+		wantOK bool
+	}{
+		{name: "seconds", status: http.StatusTooManyRequests, header: "5", want: 5 * time.Second, wantOK: true},
+		{name: "http date", status: http.StatusServiceUnavailable, header: "Mon, 01 Jan 2024 12:00:30 GMT", want: 30 * time.Second, wantOK: true},
+		{name: "date in the past", status: http.StatusServiceUnavailable, header: "Mon, 01 Jan 2024 11:00:00 GMT", want: 0, wantOK: true},
+		{name: "missing header", status: http.StatusTooManyRequests, header: "", wantOK: false},
+		{name: "garbage", status: http.StatusTooManyRequests, header: "soon", wantOK: false},
+		{name: "other status", status: http.StatusOK, header: "5", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			if tt.header != "" {
+				resp.Header.Set("Retry-After", tt.header)
+			}
+			got, ok := retryAfter(resp, now)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("retryAfter() = (%v, %v), want (%v, %v)", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestLinkChecker_Retry(t *testing.T) {
+	var flakyHits, slowHits, missingHits atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/flaky":
+			if flakyHits.Add(1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		case "/slow-once":
+			if slowHits.Add(1) == 1 {
+				time.Sleep(200 * time.Millisecond)
+			}
+		case "/missing":
+			missingHits.Add(1)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	c := NewLinkChecker()
+	c.Client = &http.Client{Timeout: 50 * time.Millisecond}
+	c.Retry = RetryPolicy{
+		MaxAttempts:   3,
+		BaseDelay:     time.Millisecond,
+		MaxDelay:      5 * time.Millisecond,
+		RetryStatuses: []int{http.StatusServiceUnavailable},
+		RetryErrors:   []ErrorClass{ErrorTimeout},
+	}
+
+	results := c.Check(context.Background(), []Link{
+		{URL: ts.URL + "/flaky"},
+		{URL: ts.URL + "/slow-once"},
+		{URL: ts.URL + "/missing"},
+	})
+
+	tests := []struct {
+		accessible bool
+		attempts   int
+	}{
+		{accessible: true, attempts: 3},
+		{accessible: true, attempts: 2},
+		{accessible: false, attempts: 1},
+	}
+	for i, want := range tests {
+		if results[i].Accessible != want.accessible || results[i].Attempts != want.attempts {
+			t.Errorf("results[%d] = %+v, want accessible=%v attempts=%d", i, results[i], want.accessible, want.attempts)
+		}
+	}
+	if got := missingHits.Load(); got != 1 {
+		t.Errorf("non-retryable 404 requested %d times, want 1", got)
+	}
+}
+
+func TestLinkChecker_RetryReleasesSlots(t *testing.T) {
+	failed := make(chan struct{})
+	var hits atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			close(failed)
+		}
+	}))
+	defer ts.Close()
+
+	c := NewLinkChecker()
+	c.Retry.BaseDelay = 400 * time.Millisecond
+	c.Retry.MaxDelay = 400 * time.Millisecond
+	gate := newHostPool().get(hostOf(ts.URL), 1, 0)
+	sem := make(chan struct{}, 1)
+
+	result := make(chan LinkResult)
+	go func() { result <- c.checkLink(context.Background(), gate, sem, Link{URL: ts.URL}) }()
+
+	// The backoff lasts at least 200ms; both slots must be free during it.
+	<-failed
+	select {
+	case gate.sem <- struct{}{}:
+		gate.release()
+	case <-time.After(150 * time.Millisecond):
+		t.Error("host slot held while waiting to retry")
+	}
+	select {
+	case sem <- struct{}{}:
+		<-sem
+	case <-time.After(150 * time.Millisecond):
+		t.Error("global slot held while waiting to retry")
+	}
+
+	if r := <-result; !r.Accessible || r.Attempts != 2 {
+		t.Errorf("result = %+v, want accessible after 2 attempts", r)
+	}
+}
+
+func TestHostPool_DropsIdleGates(t *testing.T) {
+	p := newHostPool()
+
+	a := p.get("a.example", 1, 0)
+	if again := p.get("a.example", 1, 0); again != a {
+		t.Fatal("get returned a second gate for a host in use")
+	}
+	p.put("a.example", a)
+	if len(p.gates) != 1 {
+		t.Errorf("gate dropped while still in use, %d gates left", len(p.gates))
+	}
+	p.put("a.example", a)
+	if len(p.gates) != 0 {
+		t.Errorf("idle gate kept, %d gates left", len(p.gates))
+	}
+
+	// A paused gate survives its users until the pause is over.
+	b := p.get("b.example", 1, 0)
+	b.pause(50 * time.Millisecond)
+	p.put("b.example", b)
+	if got := p.get("b.example", 1, 0); got != b {
+		t.Error("paused gate dropped before its pause ended")
+	} else {
+		p.put("b.example", got)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	p.put("c.example", p.get("c.example", 1, 0))
+	if len(p.gates) != 0 {
+		t.Errorf("gates whose pause ended were kept: %v", p.gates)
+	}
+}
+
+func TestLinkChecker_RetryExhausted(t *testing.T) {
+	var hits atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	c := NewLinkChecker()
+	c.Retry.BaseDelay = time.Millisecond
+
+	results := c.Check(context.Background(), []Link{{URL: ts.URL}})
+	if results[0].Accessible || results[0].Attempts != c.Retry.MaxAttempts {
+		t.Errorf("result = %+v, want inaccessible after %d attempts", results[0], c.Retry.MaxAttempts)
+	}
+	if got := int(hits.Load()); got != c.Retry.MaxAttempts {
+		t.Errorf("server saw %d requests, want %d", got, c.Retry.MaxAttempts)
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+	tests := []struct {
+		retry    int
+		min, max time.Duration
+	}{
+		{retry: 1, min: 50 * time.Millisecond, max: 100 * time.Millisecond},
+		{retry: 2, min: 100 * time.Millisecond, max: 200 * time.Millisecond},
+		{retry: 3, min: 150 * time.Millisecond, max: 300 * time.Millisecond},
+		{retry: 80, min: 150 * time.Millisecond, max: 300 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		for range 20 {
+			if got := p.backoff(tt.retry); got < tt.min || got > tt.max {
+				t.Errorf("backoff(%d) = %v, want within [%v, %v]", tt.retry, got, tt.min, tt.max)
+			}
+		}
+	}
+}
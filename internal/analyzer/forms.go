@@ -0,0 +1,445 @@
+package analyzer
+
+import (
+	"cmp"
+	"math"
+	"net/url"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// FormKind is the purpose a form was classified as.
+type FormKind string
+
+const (
+	FormLogin         FormKind = "login"
+	FormSignup        FormKind = "signup"
+	FormSearch        FormKind = "search"
+	FormNewsletter    FormKind = "newsletter"
+	FormCheckout      FormKind = "checkout"
+	FormPasswordReset FormKind = "password_reset"
+	FormUnknown       FormKind = "unknown"
+)
+
+// minFormConfidence is the score a kind needs before a form is classified
+// as that kind rather than FormUnknown.
+const minFormConfidence = 0.3
+
+// FormReport describes one <form> element.
+type FormReport struct {
+	Path   string `json:"path"`
+	Method string `json:"method"`
+	// Action is the resolved URL the form submits to.
+	Action string `json:"action"`
+	// PlainHTTP is set when the form submits over unencrypted http.
+	PlainHTTP    bool        `json:"plainHttp"`
+	Inputs       []FormInput `json:"inputs"`
+	HasCSRFToken bool        `json:"hasCsrfToken"`
+	Kind         FormKind    `json:"kind"`
+	Confidence   float64     `json:"confidence"`
+	// Signals lists the evidence behind Kind.
+	Signals []string `json:"signals"`
+}
+
+// FormInput is a single control in a form. Credential is set for password
+// fields and the username or email field that accompanies them.
+type FormInput struct {
+	Tag          string `json:"tag"`
+	Type         string `json:"type,omitempty"`
+	Name         string `json:"name,omitempty"`
+	ID           string `json:"id,omitempty"`
+	Autocomplete string `json:"autocomplete,omitempty"`
+	Required     bool   `json:"required,omitempty"`
+	Credential   bool   `json:"credential,omitempty"`
+}
+
+// csrfNames are substrings of hidden input names that carry anti-CSRF tokens.
+var csrfNames = []string{"csrf", "xsrf", "authenticity_token", "requestverificationtoken", "_token", "nonce"}
+
+// AnalyzeForms describes every <form> in doc. Actions are resolved against
+// the document base; a missing action submits to pageURL itself.
+func AnalyzeForms(doc *html.Node, pageURL *url.URL) []FormReport {
+	base := DocumentBase(doc, pageURL)
+	reports := []FormReport{}
+	for _, form := range findAll(doc, "form") {
+		reports = append(reports, describeForm(form, pageURL, base))
+	}
+	return reports
+}
+
+func describeForm(form *html.Node, page, base *url.URL) FormReport {
+	r := FormReport{
+		Path:   cssPath(form),
+		Method: "GET",
+		Action: page.String(),
+		Inputs: []FormInput{},
+	}
+	if m, _ := attr(form, "method"); strings.EqualFold(m, "post") || strings.EqualFold(m, "dialog") {
+		r.Method = strings.ToUpper(m)
+	}
+	if action, _ := attr(form, "action"); strings.TrimSpace(action) != "" {
+		r.Action = resolveRef(base, action)
+	}
+	if u, err := url.Parse(r.Action); err == nil {
+		r.PlainHTTP = strings.EqualFold(u.Scheme, "http")
+	}
+
+	f := collectFormFields(form)
+	for _, in := range f.inputs {
+		if in.Type == "hidden" && isCSRFName(in.Name) {
+			r.HasCSRFToken = true
+		}
+	}
+	r.Inputs = f.inputs
+
+	r.Kind, r.Confidence, r.Signals = classifyForm(form, r, f)
+	return r
+}
+
+func isCSRFName(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range csrfNames {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// formFields is what classification needs to know about a form's controls.
+type formFields struct {
+	inputs    []FormInput
+	passwords int
+	// submitText is the text of the form's submit buttons.
+	submitText []string
+}
+
+func collectFormFields(form *html.Node) formFields {
+	var f formFields
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "input", "select", "textarea":
+				in := formInput(n)
+				f.inputs = append(f.inputs, in)
+				switch in.Type {
+				case "password":
+					f.passwords++
+				case "submit", "image":
+					v, _ := attr(n, "value")
+					if in.Type == "image" {
+						v, _ = attr(n, "alt")
+					}
+					f.submitText = append(f.submitText, v)
+				}
+			case "button":
+				// Script-driven forms submit from type="button" too.
+				if t, _ := attr(n, "type"); !strings.EqualFold(t, "reset") {
+					f.submitText = append(f.submitText, accessibleName(n))
+				}
+			default:
+				if strings.EqualFold(attrValue(n, "role"), "button") {
+					f.submitText = append(f.submitText, accessibleName(n))
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(form)
+
+	// Mark the password fields and the identifier field preceding the first
+	// one as credentials.
+	for i, in := range f.inputs {
+		if in.Type != "password" {
+			continue
+		}
+		f.inputs[i].Credential = true
+		for j := i - 1; j >= 0; j-- {
+			if isIdentifierInput(f.inputs[j]) {
+				f.inputs[j].Credential = true
+				break
+			}
+		}
+	}
+	return f
+}
+
+func formInput(n *html.Node) FormInput {
+	in := FormInput{Tag: n.Data}
+	in.Type, _ = attr(n, "type")
+	in.Type = strings.ToLower(strings.TrimSpace(in.Type))
+	if n.Data == "input" && in.Type == "" {
+		in.Type = "text"
+	}
+	in.Name, _ = attr(n, "name")
+	in.ID, _ = attr(n, "id")
+	in.Autocomplete, _ = attr(n, "autocomplete")
+	in.Autocomplete = strings.ToLower(strings.TrimSpace(in.Autocomplete))
+	_, in.Required = attr(n, "required")
+	return in
+}
+
+// isIdentifierInput reports whether in looks like a username or email field.
+func isIdentifierInput(in FormInput) bool {
+	if in.Tag != "input" {
+		return false
+	}
+	switch in.Type {
+	case "email":
+		return true
+	case "text", "tel":
+	default:
+		return false
+	}
+	if hasToken(in.Autocomplete, "username") || hasToken(in.Autocomplete, "email") {
+		return true
+	}
+	words := formWords(in.Name + " " + in.ID)
+	return containsWord(words, "user", "username", "login", "email", "mail", "account", "identifier")
+}
+
+// formClassifier accumulates weighted evidence for each form kind.
+type formClassifier struct {
+	scores  map[FormKind]float64
+	signals map[FormKind][]string
+}
+
+func (c *formClassifier) add(kind FormKind, weight float64, signal string) {
+	c.scores[kind] += weight
+	c.signals[kind] = append(c.signals[kind], signal)
+}
+
+// classifyForm scores form against every kind and returns the best one, its
+// confidence in [0, 1] and the signals that contributed to it.
+func classifyForm(form *html.Node, r FormReport, f formFields) (FormKind, float64, []string) {
+	c := formClassifier{scores: make(map[FormKind]float64), signals: make(map[FormKind][]string)}
+
+	submit := formWords(strings.Join(f.submitText, " "))
+	action := ""
+	if u, err := url.Parse(r.Action); err == nil {
+		action = formWords(u.Path)
+	}
+	attrs := formWords(attrValue(form, "id") + " " + attrValue(form, "name") + " " + attrValue(form, "class"))
+
+	var hasEmail, hasSearch, hasCard, hasAddress, hasNewPassword, hasCurrentPassword, hasUsername bool
+	var searchName bool
+	for _, in := range f.inputs {
+		switch {
+		case in.Type == "email" || hasToken(in.Autocomplete, "email"):
+			hasEmail = true
+		case in.Type == "search":
+			hasSearch = true
+		}
+		ac := in.Autocomplete
+		switch {
+		case hasToken(ac, "new-password"):
+			hasNewPassword = true
+		case hasToken(ac, "current-password"):
+			hasCurrentPassword = true
+		case hasToken(ac, "username"):
+			hasUsername = true
+		case strings.Contains(ac, "cc-"):
+			hasCard = true
+		case strings.Contains(ac, "address") || strings.Contains(ac, "postal-code"):
+			hasAddress = true
+		}
+		name := strings.ToLower(in.Name)
+		if containsWord(formWords(name+" "+in.ID), "card", "cardnumber", "cvc", "cvv", "expiry") {
+			hasCard = true
+		}
+		if in.Tag == "input" && (name == "q" || name == "s" || name == "query" || name == "search" || name == "keyword") {
+			searchName = true
+		}
+	}
+
+	// Login. Field names alone are not evidence: "login_email" is as likely
+	// to be a newsletter field as a username.
+	if f.passwords == 1 && !hasNewPassword {
+		c.add(FormLogin, 0.5, "single password field")
+	}
+	if f.passwords == 0 && hasPasswordName(f.inputs) {
+		c.add(FormLogin, 0.5, "field named password")
+	}
+	if hasCurrentPassword {
+		c.add(FormLogin, 0.4, `autocomplete="current-password"`)
+	}
+	if hasUsername {
+		if f.passwords > 0 {
+			c.add(FormLogin, 0.2, `autocomplete="username"`)
+		} else {
+			c.add(FormLogin, 0.3, `autocomplete="username" without password (identifier-first)`)
+		}
+	}
+	loginSubmit := containsWord(submit, "log in", "login", "sign in", "signin", "log on", "logon")
+	loginAction := containsWord(action, "login", "signin", "sign in", "log in", "session", "sessions", "auth", "authenticate")
+	if loginSubmit {
+		c.add(FormLogin, 0.3, "login submit text")
+	}
+	if loginAction {
+		c.add(FormLogin, 0.3, "login action path")
+	}
+	if isIdentifierStep(f) && (loginSubmit || loginAction || containsWord(submit, "next", "continue")) {
+		c.add(FormLogin, 0.2, "identifier-first step")
+	}
+
+	// Signup.
+	if f.passwords >= 2 {
+		c.add(FormSignup, 0.4, "password confirmation field")
+	}
+	if hasNewPassword {
+		c.add(FormSignup, 0.3, `autocomplete="new-password"`)
+	}
+	if containsWord(submit, "sign up", "signup", "register", "create account", "create an account", "join") {
+		c.add(FormSignup, 0.4, "signup submit text")
+	}
+	if containsWord(action, "signup", "sign up", "register", "registration", "join") {
+		c.add(FormSignup, 0.3, "signup action path")
+	}
+
+	// Password reset.
+	if containsWord(submit, "reset", "forgot", "recover", "send reset link") {
+		c.add(FormPasswordReset, 0.4, "password reset submit text")
+	}
+	if containsWord(action, "reset", "forgot", "recover", "recovery") {
+		c.add(FormPasswordReset, 0.4, "password reset action path")
+	}
+	if hasNewPassword && f.passwords > 0 && containsWord(action, "reset", "password") {
+		c.add(FormPasswordReset, 0.2, "new password on reset path")
+	}
+
+	// Search.
+	if hasSearch {
+		c.add(FormSearch, 0.5, `input type="search"`)
+	}
+	if role := attrValue(form, "role"); strings.EqualFold(role, "search") || hasSearchAncestor(form) {
+		c.add(FormSearch, 0.4, `role="search"`)
+	}
+	if searchName {
+		c.add(FormSearch, 0.3, "search query field name")
+	}
+	if containsWord(action, "search", "find") || containsWord(attrs, "search") {
+		c.add(FormSearch, 0.3, "search action path")
+	}
+	if containsWord(submit, "search", "find", "go") {
+		c.add(FormSearch, 0.2, "search submit text")
+	}
+
+	// Newsletter.
+	if containsWord(submit, "subscribe", "newsletter") {
+		c.add(FormNewsletter, 0.4, "newsletter submit text")
+	}
+	if containsWord(action, "subscribe", "newsletter", "subscription") || containsWord(attrs, "newsletter", "subscribe") {
+		c.add(FormNewsletter, 0.4, "newsletter action path")
+	}
+	if hasEmail && f.passwords == 0 && len(visibleInputs(f.inputs)) <= 2 {
+		c.add(FormNewsletter, 0.2, "lone email field")
+	}
+
+	// Checkout.
+	if hasCard {
+		c.add(FormCheckout, 0.5, "payment card field")
+	}
+	if hasAddress {
+		c.add(FormCheckout, 0.2, "address autocomplete")
+	}
+	if containsWord(submit, "checkout", "pay", "order", "purchase", "buy") || containsWord(submit, "place order") {
+		c.add(FormCheckout, 0.3, "checkout submit text")
+	}
+	if containsWord(action, "checkout", "payment", "order", "cart") {
+		c.add(FormCheckout, 0.3, "checkout action path")
+	}
+
+	return c.best()
+}
+
+func (c *formClassifier) best() (FormKind, float64, []string) {
+	kinds := []FormKind{FormLogin, FormSignup, FormPasswordReset, FormSearch, FormNewsletter, FormCheckout}
+	best := slices.MaxFunc(kinds, func(a, b FormKind) int {
+		// MaxFunc keeps the first maximum, so ties go to the earlier kind.
+		return cmp.Compare(c.scores[a], c.scores[b])
+	})
+	score := math.Round(min(c.scores[best], 1)*100) / 100
+	if score < minFormConfidence {
+		return FormUnknown, score, []string{}
+	}
+	return best, score, c.signals[best]
+}
+
+// isIdentifierStep reports whether the only field in f is a username or
+// email field, as on the first step of an identifier-first login.
+func isIdentifierStep(f formFields) bool {
+	visible := visibleInputs(f.inputs)
+	return f.passwords == 0 && len(visible) == 1 && isIdentifierInput(visible[0])
+}
+
+// hasPasswordName reports whether a visible text field is named like a
+// password, as on pages that mask the value with script.
+func hasPasswordName(inputs []FormInput) bool {
+	for _, in := range inputs {
+		if in.Tag == "input" && in.Type == "text" && containsWord(formWords(in.Name+" "+in.ID), "password", "passwd", "pwd") {
+			return true
+		}
+	}
+	return false
+}
+
+func visibleInputs(inputs []FormInput) []FormInput {
+	var out []FormInput
+	for _, in := range inputs {
+		switch in.Type {
+		case "hidden", "submit", "button", "reset", "image":
+		default:
+			out = append(out, in)
+		}
+	}
+	return out
+}
+
+func hasSearchAncestor(n *html.Node) bool {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Type != html.ElementNode {
+			continue
+		}
+		if p.Data == "search" || strings.EqualFold(attrValue(p, "role"), "search") {
+			return true
+		}
+	}
+	return false
+}
+
+func attrValue(n *html.Node, key string) string {
+	v, _ := attr(n, key)
+	return v
+}
+
+// formWords lowercases s and splits it into words on anything that is not a
+// letter or digit, returning them space-separated with a leading and
+// trailing space so that whole words can be matched with strings.Contains.
+func formWords(s string) string {
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !('a' <= r && r <= 'z' || '0' <= r && r <= '9' || r > 127)
+	})
+	return " " + strings.Join(fields, " ") + " "
+}
+
+// containsWord reports whether words, as returned by formWords, contains any
+// of targets. A target may itself be several space-separated words.
+func containsWord(words string, targets ...string) bool {
+	for _, w := range targets {
+		if strings.Contains(words, " "+w+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// hasToken reports whether the space-separated autocomplete value ac
+// contains token.
+func hasToken(ac, token string) bool {
+	return slices.Contains(strings.Fields(ac), token)
+}
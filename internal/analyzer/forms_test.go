@@ -0,0 +1,110 @@
+package analyzer
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestAnalyzeForms_Details(t *testing.T) {
+	doc := parseHTML(t, `<html><head><base href="https://example.com/app/"></head><body>
+		<form method="post" action="http://example.com/session">
+			<input type="hidden" name="authenticity_token" value="x">
+			<input type="email" name="email" autocomplete="username" required>
+			<input type="password" name="pw" autocomplete="current-password">
+			<button>Sign in</button>
+		</form>
+		<form><input name="q"></form>
+	</body></html>`)
+	page, _ := url.Parse("https://example.com/account")
+
+	got := AnalyzeForms(doc, page)
+
+	if len(got) != 2 {
+		t.Fatalf("got %d forms, want 2", len(got))
+	}
+	login := got[0]
+	if login.Method != "POST" || login.Action != "http://example.com/session" || !login.PlainHTTP {
+		t.Errorf("forms[0] = %s %s plainHttp=%v, want POST over plain http", login.Method, login.Action, login.PlainHTTP)
+	}
+	if !login.HasCSRFToken {
+		t.Error("forms[0].HasCSRFToken = false, want true")
+	}
+	if len(login.Inputs) != 3 {
+		t.Fatalf("forms[0] has %d inputs, want 3", len(login.Inputs))
+	}
+	email, pw := login.Inputs[1], login.Inputs[2]
+	if !email.Credential || !pw.Credential || login.Inputs[0].Credential {
+		t.Errorf("credential flags = %v %v %v, want false true true",
+			login.Inputs[0].Credential, email.Credential, pw.Credential)
+	}
+	if email.Autocomplete != "username" || !email.Required {
+		t.Errorf("email input = %+v, want autocomplete username and required", email)
+	}
+	if login.Kind != FormLogin || login.Confidence != 1 || len(login.Signals) == 0 {
+		t.Errorf("forms[0] classified %s (%.2f, %q), want login with full confidence", login.Kind, login.Confidence, login.Signals)
+	}
+
+	search := got[1]
+	if search.Method != "GET" || search.Action != "https://example.com/account" || search.PlainHTTP {
+		t.Errorf("forms[1] = %s %s, want GET to the page itself", search.Method, search.Action)
+	}
+	if search.HasCSRFToken {
+		t.Error("forms[1].HasCSRFToken = true, want false")
+	}
+}
+
+func TestAnalyzeForms_Classification(t *testing.T) {
+	tests := []struct {
+		name string
+		form string
+		want FormKind
+	}{
+		{
+			name: "signup",
+			form: `<form action="/register"><input type="email" name="email">
+				<input type="password" name="pw" autocomplete="new-password">
+				<input type="password" name="pw2" autocomplete="new-password">
+				<button>Create account</button></form>`,
+			want: FormSignup,
+		},
+		{
+			name: "search",
+			form: `<form role="search" action="/search"><input type="search" name="q"><button>Search</button></form>`,
+			want: FormSearch,
+		},
+		{
+			name: "newsletter",
+			form: `<form action="/newsletter"><input type="email" name="email"><button>Subscribe</button></form>`,
+			want: FormNewsletter,
+		},
+		{
+			name: "checkout",
+			form: `<form action="/checkout/pay"><input name="cardnumber" autocomplete="cc-number">
+				<input name="cvc"><button>Place order</button></form>`,
+			want: FormCheckout,
+		},
+		{
+			name: "password reset",
+			form: `<form action="/password/forgot"><input type="email" name="email"><button>Send reset link</button></form>`,
+			want: FormPasswordReset,
+		},
+		{
+			name: "unknown",
+			form: `<form action="/contact"><input name="name"><textarea name="message"></textarea><button>Send</button></form>`,
+			want: FormUnknown,
+		},
+	}
+	page, _ := url.Parse("https://example.com/")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parseHTML(t, "<html><body>"+tt.form+"</body></html>")
+			got := AnalyzeForms(doc, page)
+			if len(got) != 1 {
+				t.Fatalf("got %d forms, want 1", len(got))
+			}
+			if got[0].Kind != tt.want {
+				t.Errorf("Kind = %s (%.2f, %q), want %s", got[0].Kind, got[0].Confidence, got[0].Signals, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package analyzer
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// FragmentReport lists the in-page links whose target is missing.
+type FragmentReport struct {
+	// Checked is the number of in-page fragment links found.
+	Checked int              `json:"checked"`
+	Broken  []BrokenFragment `json:"broken"`
+}
+
+// BrokenFragment is an in-page link pointing at a fragment that no element
+// in the document declares.
+type BrokenFragment struct {
+	Href     string `json:"href"`
+	Fragment string `json:"fragment"`
+	Text     string `json:"text"`
+}
+
+// CheckFragments finds links to fragments of the page itself — both "#x" and
+// "page#x" forms — and reports those whose target is neither an element id
+// nor an <a name>. "#" and "#top" always scroll to the top and are valid.
+func CheckFragments(doc *html.Node, pageURL *url.URL) FragmentReport {
+	targets := make(map[string]bool)
+	collectFragmentTargets(doc, targets)
+
+	report := FragmentReport{Broken: []BrokenFragment{}}
+	f := fragmentFinder{base: DocumentBase(doc, pageURL), page: NormalizeURL(pageURL.String(), NormalizeOptions{})}
+	f.walk(doc, func(n *html.Node, href, fragment string) {
+		report.Checked++
+		if fragment == "" || strings.EqualFold(fragment, "top") || targets[fragment] {
+			return
+		}
+		report.Broken = append(report.Broken, BrokenFragment{
+			Href:     href,
+			Fragment: fragment,
+			Text:     innerText(n),
+		})
+	})
+	return report
+}
+
+func collectFragmentTargets(n *html.Node, targets map[string]bool) {
+	if n.Type == html.ElementNode {
+		if id, ok := attr(n, "id"); ok && id != "" {
+			targets[id] = true
+		}
+		if n.Data == "a" {
+			if name, ok := attr(n, "name"); ok && name != "" {
+				targets[name] = true
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		collectFragmentTargets(c, targets)
+	}
+}
+
+type fragmentFinder struct {
+	base *url.URL
+	page string
+}
+
+// walk calls fn for every <a> or <area> linking to a fragment of the page.
+func (f fragmentFinder) walk(n *html.Node, fn func(n *html.Node, href, fragment string)) {
+	if n.Type == html.ElementNode && (n.Data == "a" || n.Data == "area") {
+		if href, ok := attr(n, "href"); ok {
+			if fragment, ok := f.samePageFragment(strings.TrimSpace(href)); ok {
+				fn(n, href, fragment)
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		f.walk(c, fn)
+	}
+}
+
+func (f fragmentFinder) samePageFragment(href string) (string, bool) {
+	if !strings.Contains(href, "#") {
+		return "", false
+	}
+	parsed, err := url.Parse(href)
+	if err != nil {
+		return "", false
+	}
+
+	resolved := f.base.ResolveReference(parsed)
+	if NormalizeURL(resolved.String(), NormalizeOptions{}) != f.page {
+		return "", false
+	}
+	return resolved.Fragment, true
+}
@@ -0,0 +1,72 @@
+package analyzer
+
+import "testing"
+
+func TestCheckFragments(t *testing.T) {
+	page := mustParseURL(t, "http://example.com/guide?lang=en")
+
+	t.Run("existing and missing targets", func(t *testing.T) {
+		doc := parseHTML(t, `<html><body>
+			<nav>
+				<a href="#intro">Intro</a>
+				<a href="#setup">Setup</a>
+				<a href="#legacy">Legacy</a>
+				<a href="#missing">Missing</a>
+			</nav>
+			<section id="intro"></section>
+			<h2 id="setup">Setup</h2>
+			<a name="legacy"></a>
+		</body></html>`)
+		report := CheckFragments(doc, page)
+		if report.Checked != 4 {
+			t.Errorf("Checked = %d, want 4", report.Checked)
+		}
+		if len(report.Broken) != 1 {
+			t.Fatalf("got %d broken fragments, want 1: %+v", len(report.Broken), report.Broken)
+		}
+		want := BrokenFragment{Href: "#missing", Fragment: "missing", Text: "Missing"}
+		if report.Broken[0] != want {
+			t.Errorf("Broken[0] = %+v, want %+v", report.Broken[0], want)
+		}
+	})
+
+	t.Run("same-page absolute and relative forms", func(t *testing.T) {
+		doc := parseHTML(t, `<html><body>
+			<a href="http://EXAMPLE.com:80/guide?lang=en#faq">FAQ</a>
+			<a href="guide?lang=en#ok">OK</a>
+			<a href="/guide?lang=de#faq">Other language</a>
+			<a href="/other#faq">Other page</a>
+			<div id="ok"></div>
+		</body></html>`)
+		report := CheckFragments(doc, page)
+		if report.Checked != 2 {
+			t.Errorf("Checked = %d, want 2", report.Checked)
+		}
+		if len(report.Broken) != 1 || report.Broken[0].Fragment != "faq" {
+			t.Errorf("Broken = %+v, want only the absolute #faq link", report.Broken)
+		}
+	})
+
+	t.Run("top of page and percent-encoded targets", func(t *testing.T) {
+		doc := parseHTML(t, `<html><body>
+			<a href="#">Top</a>
+			<a href="#top">Top</a>
+			<a href="#caf%C3%A9">Café</a>
+			<p id="café"></p>
+		</body></html>`)
+		report := CheckFragments(doc, page)
+		if len(report.Broken) != 0 {
+			t.Errorf("Broken = %+v, want none", report.Broken)
+		}
+	})
+
+	t.Run("base href changes what counts as same page", func(t *testing.T) {
+		doc := parseHTML(t, `<html><head><base href="/docs/"></head><body>
+			<a href="#missing">Elsewhere</a>
+		</body></html>`)
+		report := CheckFragments(doc, page)
+		if report.Checked != 0 || len(report.Broken) != 0 {
+			t.Errorf("report = %+v, want no in-page links", report)
+		}
+	})
+}
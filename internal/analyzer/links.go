@@ -2,63 +2,264 @@ package analyzer
 
 import (
 	"context"
-	"fmt"
-	"net/http"
 	"net/url"
 	"strings"
-	"sync"
-	"time"
 
 	"golang.org/x/net/html"
 )
 
-var linkClient = &http.Client{
-	Timeout: 5 * time.Second,
-	CheckRedirect: func(req *http.Request, via []*http.Request) error {
-		if len(via) >= 10 {
-			return fmt.Errorf("too many redirects")
-		}
-		return nil
-	},
-}
+// ResourceKind names what a referenced URL is used for in the page.
+type ResourceKind string
+
+const (
+	KindAnchor     ResourceKind = "anchor"
+	KindImage      ResourceKind = "image"
+	KindScript     ResourceKind = "script"
+	KindStylesheet ResourceKind = "stylesheet"
+	KindMedia      ResourceKind = "media"
+	KindFrame      ResourceKind = "frame"
+	KindForm       ResourceKind = "form"
+)
 
 type Link struct {
 	URL        string
+	Text       string
+	Kind       ResourceKind
 	IsInternal bool
+	// Occurrences is how many times the reference appears in the page.
+	Occurrences int
 }
 
 var skipSchemes = map[string]bool{
 	"mailto":     true,
 	"javascript": true,
 	"tel":        true,
+	"data":       true,
+	"blob":       true,
+}
+
+type resourceAttr struct {
+	name string
+	kind ResourceKind
+}
+
+// resourceAttrs lists, per element, the attributes that hold a URL and the
+// kind of resource each references. <link>, <source> and <input> depend on
+// other attributes and are handled in resourceAttrsFor.
+var resourceAttrs = map[string][]resourceAttr{
+	"a":      {{"href", KindAnchor}},
+	"area":   {{"href", KindAnchor}},
+	"img":    {{"src", KindImage}, {"srcset", KindImage}},
+	"script": {{"src", KindScript}},
+	"iframe": {{"src", KindFrame}},
+	"frame":  {{"src", KindFrame}},
+	"video":  {{"src", KindMedia}, {"poster", KindImage}},
+	"audio":  {{"src", KindMedia}},
+	"track":  {{"src", KindMedia}},
+	"embed":  {{"src", KindMedia}},
+	"object": {{"data", KindMedia}},
+	"form":   {{"action", KindForm}},
 }
 
-func ClassifyLinks(doc *html.Node, baseURL *url.URL) []Link {
+// preloadKinds maps the "as" attribute of preload-style <link> elements to a
+// resource kind.
+var preloadKinds = map[string]ResourceKind{
+	"style":    KindStylesheet,
+	"script":   KindScript,
+	"image":    KindImage,
+	"audio":    KindMedia,
+	"video":    KindMedia,
+	"track":    KindMedia,
+	"font":     KindMedia,
+	"document": KindFrame,
+}
+
+// ClassifyLinks extracts every resource the document references — anchors,
+// images, scripts, stylesheets, media, frames and form actions. References
+// are resolved against the document base (see DocumentBase) and classified
+// as internal when they point at pageURL's host.
+func ClassifyLinks(doc *html.Node, pageURL *url.URL) []Link {
+	return ClassifyLinksInScope(doc, pageURL, Scope{Mode: ScopeHost})
+}
+
+// ClassifyLinksInScope is like ClassifyLinks but classifies references as
+// internal according to scope.
+func ClassifyLinksInScope(doc *html.Node, pageURL *url.URL, scope Scope) []Link {
+	r := linkResolver{base: DocumentBase(doc, pageURL), page: pageURL, scope: scope}
 	var links []Link
-	collectLinks(doc, baseURL, &links)
+	collectLinks(doc, r, &links)
 	return links
 }
 
-func collectLinks(n *html.Node, baseURL *url.URL, links *[]Link) {
-	if n.Type == html.ElementNode && n.Data == "a" {
-		if link, ok := extractLink(n, baseURL); ok {
-			*links = append(*links, link)
+// DocumentBase returns the URL relative references in doc resolve against:
+// the href of the first <base> element, itself resolved against pageURL, or
+// pageURL when there is no usable <base>.
+func DocumentBase(doc *html.Node, pageURL *url.URL) *url.URL {
+	n := findBaseHref(doc)
+	if n == nil {
+		return pageURL
+	}
+
+	href, _ := attr(n, "href")
+	parsed, err := url.Parse(strings.TrimSpace(href))
+	if err != nil || skipSchemes[parsed.Scheme] {
+		return pageURL
+	}
+	return pageURL.ResolveReference(parsed)
+}
+
+func findBaseHref(n *html.Node) *html.Node {
+	if n.Type == html.ElementNode && n.Data == "base" {
+		if _, ok := attr(n, "href"); ok {
+			return n
 		}
 	}
 	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		collectLinks(c, baseURL, links)
+		if found := findBaseHref(c); found != nil {
+			return found
+		}
 	}
+	return nil
 }
 
-func extractLink(n *html.Node, baseURL *url.URL) (Link, bool) {
-	var href string
-	for _, a := range n.Attr {
-		if a.Key == "href" {
-			href = strings.TrimSpace(a.Val)
+// linkResolver resolves references against the document base and classifies
+// them against the page they were found on.
+type linkResolver struct {
+	base  *url.URL
+	page  *url.URL
+	scope Scope
+}
+
+func collectLinks(n *html.Node, r linkResolver, links *[]Link) {
+	if n.Type == html.ElementNode {
+		*links = append(*links, extractLinks(n, r)...)
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		collectLinks(c, r, links)
+	}
+}
+
+func extractLinks(n *html.Node, r linkResolver) []Link {
+	var links []Link
+	for _, ra := range resourceAttrsFor(n) {
+		val, ok := attr(n, ra.name)
+		if !ok {
+			continue
+		}
+
+		refs := []string{val}
+		if ra.name == "srcset" {
+			refs = parseSrcset(val)
+		}
+		for _, ref := range refs {
+			if link, ok := r.extractLink(ref); ok {
+				link.Kind = ra.kind
+				link.Text = linkText(n)
+				links = append(links, link)
+			}
+		}
+	}
+	return links
+}
+
+func resourceAttrsFor(n *html.Node) []resourceAttr {
+	switch n.Data {
+	case "link":
+		kind, ok := linkRelKind(n)
+		if !ok {
+			return nil
+		}
+		return []resourceAttr{{"href", kind}}
+	case "source":
+		kind := KindMedia
+		if n.Parent != nil && n.Parent.Type == html.ElementNode && n.Parent.Data == "picture" {
+			kind = KindImage
+		}
+		return []resourceAttr{{"src", kind}, {"srcset", kind}}
+	case "input":
+		if t, _ := attr(n, "type"); strings.EqualFold(t, "image") {
+			return []resourceAttr{{"src", KindImage}}
+		}
+		return nil
+	default:
+		return resourceAttrs[n.Data]
+	}
+}
+
+// linkRelKind reports the kind of resource a <link> element loads, if any.
+func linkRelKind(n *html.Node) (ResourceKind, bool) {
+	rel, _ := attr(n, "rel")
+	for _, r := range strings.Fields(strings.ToLower(rel)) {
+		switch r {
+		case "stylesheet":
+			return KindStylesheet, true
+		case "icon", "apple-touch-icon", "apple-touch-icon-precomposed", "mask-icon":
+			return KindImage, true
+		case "preload", "prefetch":
+			as, _ := attr(n, "as")
+			kind, ok := preloadKinds[strings.ToLower(as)]
+			return kind, ok
+		case "modulepreload":
+			return KindScript, true
+		}
+	}
+	return "", false
+}
+
+func linkText(n *html.Node) string {
+	switch n.Data {
+	case "a":
+		return innerText(n)
+	case "img", "area", "input":
+		alt, _ := attr(n, "alt")
+		return strings.TrimSpace(alt)
+	case "iframe", "frame":
+		title, _ := attr(n, "title")
+		return strings.TrimSpace(title)
+	}
+	return ""
+}
+
+// parseSrcset returns the URLs of the image candidates in a srcset value.
+func parseSrcset(v string) []string {
+	var urls []string
+	for v != "" {
+		v = strings.TrimLeft(v, " \t\n\r\f,")
+		if v == "" {
 			break
 		}
+
+		end := strings.IndexAny(v, " \t\n\r\f")
+		if end < 0 {
+			end = len(v)
+		}
+		u := v[:end]
+		v = v[end:]
+
+		if trimmed := strings.TrimRight(u, ","); trimmed != u {
+			// A trailing comma ends a candidate without descriptors.
+			u = trimmed
+		} else if comma := strings.IndexByte(v, ','); comma >= 0 {
+			v = v[comma+1:]
+		} else {
+			v = ""
+		}
+		urls = append(urls, u)
 	}
+	return urls
+}
 
+func attr(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func (r linkResolver) extractLink(ref string) (Link, bool) {
+	href := strings.TrimSpace(ref)
 	if href == "" || strings.HasPrefix(href, "#") {
 		return Link{}, false
 	}
@@ -72,56 +273,29 @@ func extractLink(n *html.Node, baseURL *url.URL) (Link, bool) {
 		return Link{}, false
 	}
 
-	resolved := baseURL.ResolveReference(parsed)
+	resolved := r.base.ResolveReference(parsed)
 
 	return Link{
-		URL:        resolved.String(),
-		IsInternal: strings.EqualFold(resolved.Host, baseURL.Host),
+		URL:         resolved.String(),
+		IsInternal:  r.scope.Contains(r.page, resolved),
+		Occurrences: 1,
 	}, true
 }
 
 func CountInaccessibleLinks(ctx context.Context, links []Link, workers int) int {
-	if len(links) == 0 {
-		return 0
-	}
-
-	var (
-		mu    sync.Mutex
-		count int
-		wg    sync.WaitGroup
-	)
-	sem := make(chan struct{}, workers)
-
-	for _, l := range links {
-		wg.Add(1)
-		go func(rawURL string) {
-			defer wg.Done()
-			sem <- struct{}{}
-			defer func() { <-sem }()
-
-			if !isAccessible(ctx, rawURL) {
-				mu.Lock()
-				count++
-				mu.Unlock()
-			}
-		}(l.URL)
+	var count int
+	for _, r := range CheckLinks(ctx, links, workers) {
+		if r.Inaccessible() {
+			count++
+		}
 	}
-
-	wg.Wait()
 	return count
 }
 
-func isAccessible(ctx context.Context, rawURL string) bool {
-	req, err := http.NewRequestWithContext(ctx, http.MethodHead, rawURL, nil)
-	if err != nil {
-		return false
-	}
-
-	resp, err := linkClient.Do(req)
-	if err != nil {
-		return false
-	}
-	resp.Body.Close()
-
-	return resp.StatusCode < 400
+// CheckLinks checks links with the default LinkChecker settings and the given
+// number of workers.
+func CheckLinks(ctx context.Context, links []Link, workers int) []LinkResult {
+	c := NewLinkChecker()
+	c.Workers = workers
+	return c.Check(ctx, links)
 }
@@ -107,3 +107,319 @@ func TestCountInaccessibleLinks_Empty(t *testing.T) {
 		t.Errorf("CountInaccessibleLinks(nil) = %d, want 0", count)
 	}
 }
+
+func TestClassifyLinks_BaseHref(t *testing.T) {
+	base := mustParseURL(t, "http://example.com/page")
+
+	t.Run("absolute base on same host", func(t *testing.T) {
+		doc := parseHTML(t, `<html><head><base href="http://example.com/docs/"></head><body>
+			<a href="intro">Intro</a>
+			<a href="/contact">Contact</a>
+		</body></html>`)
+		links := ClassifyLinks(doc, base)
+		if len(links) != 2 {
+			t.Fatalf("got %d links, want 2", len(links))
+		}
+		if links[0].URL != "http://example.com/docs/intro" {
+			t.Errorf("link[0].URL = %q, want %q", links[0].URL, "http://example.com/docs/intro")
+		}
+		if !links[0].IsInternal {
+			t.Error("link resolved against same-host base should be internal")
+		}
+		if links[1].URL != "http://example.com/contact" {
+			t.Errorf("link[1].URL = %q, want %q", links[1].URL, "http://example.com/contact")
+		}
+	})
+
+	t.Run("relative base resolved against page URL", func(t *testing.T) {
+		doc := parseHTML(t, `<html><head><base href="/shop/"></head><body>
+			<a href="cart">Cart</a>
+		</body></html>`)
+		links := ClassifyLinks(doc, base)
+		if len(links) != 1 {
+			t.Fatalf("got %d links, want 1", len(links))
+		}
+		if links[0].URL != "http://example.com/shop/cart" {
+			t.Errorf("link.URL = %q, want %q", links[0].URL, "http://example.com/shop/cart")
+		}
+		if !links[0].IsInternal {
+			t.Error("link resolved against relative base should be internal")
+		}
+	})
+
+	t.Run("base on another host makes relative links external", func(t *testing.T) {
+		doc := parseHTML(t, `<html><head><base href="https://cdn.example.net/assets/"></head><body>
+			<a href="file.pdf">File</a>
+			<a href="http://example.com/about">About</a>
+		</body></html>`)
+		links := ClassifyLinks(doc, base)
+		if len(links) != 2 {
+			t.Fatalf("got %d links, want 2", len(links))
+		}
+		if links[0].URL != "https://cdn.example.net/assets/file.pdf" {
+			t.Errorf("link[0].URL = %q, want %q", links[0].URL, "https://cdn.example.net/assets/file.pdf")
+		}
+		if links[0].IsInternal {
+			t.Error("link resolved against another host should be external")
+		}
+		if !links[1].IsInternal {
+			t.Error("absolute link to the page host should stay internal")
+		}
+	})
+
+	t.Run("first base with href wins", func(t *testing.T) {
+		doc := parseHTML(t, `<html><head>
+			<base target="_blank">
+			<base href="/first/">
+			<base href="/second/">
+		</head><body><a href="x">X</a></body></html>`)
+		links := ClassifyLinks(doc, base)
+		if len(links) != 1 {
+			t.Fatalf("got %d links, want 1", len(links))
+		}
+		if links[0].URL != "http://example.com/first/x" {
+			t.Errorf("link.URL = %q, want %q", links[0].URL, "http://example.com/first/x")
+		}
+	})
+
+	t.Run("unusable base falls back to page URL", func(t *testing.T) {
+		doc := parseHTML(t, `<html><head><base href="javascript:void(0)"></head><body>
+			<a href="sub">Sub</a>
+		</body></html>`)
+		links := ClassifyLinks(doc, base)
+		if len(links) != 1 {
+			t.Fatalf("got %d links, want 1", len(links))
+		}
+		if links[0].URL != "http://example.com/sub" {
+			t.Errorf("link.URL = %q, want %q", links[0].URL, "http://example.com/sub")
+		}
+	})
+}
+
+func TestClassifyLinks_AnchorText(t *testing.T) {
+	base := mustParseURL(t, "http://example.com/page")
+	doc := parseHTML(t, `<html><body>
+		<a href="/about">  About
+			<span>us</span></a>
+	</body></html>`)
+	links := ClassifyLinks(doc, base)
+	if len(links) != 1 {
+		t.Fatalf("got %d links, want 1", len(links))
+	}
+	if links[0].Text != "About us" {
+		t.Errorf("link.Text = %q, want %q", links[0].Text, "About us")
+	}
+}
+
+func TestCheckLinks(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.WriteHeader(http.StatusOK)
+		case "/moved":
+			http.Redirect(w, r, "/moved-again", http.StatusMovedPermanently)
+		case "/moved-again":
+			http.Redirect(w, r, "/ok", http.StatusFound)
+		case "/notfound":
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closedURL := closed.URL
+	closed.Close()
+
+	links := []Link{
+		{URL: ts.URL + "/ok", Text: "OK", IsInternal: true},
+		{URL: ts.URL + "/moved", Text: "Moved", IsInternal: true},
+		{URL: ts.URL + "/notfound", Text: "Missing", IsInternal: false},
+		{URL: closedURL + "/gone", Text: "Gone", IsInternal: false},
+	}
+
+	results := CheckLinks(context.Background(), links, 2)
+	if len(results) != len(links) {
+		t.Fatalf("got %d results, want %d", len(results), len(links))
+	}
+
+	tests := []struct {
+		accessible bool
+		status     int
+		errClass   ErrorClass
+		redirects  int
+	}{
+		{accessible: true, status: http.StatusOK, errClass: ErrorNone},
+		{accessible: true, status: http.StatusOK, errClass: ErrorNone, redirects: 2},
+		{accessible: false, status: http.StatusNotFound, errClass: ErrorStatus},
+		{accessible: false, status: 0, errClass: ErrorRefused},
+	}
+	for i, want := range tests {
+		got := results[i]
+		if got.URL != links[i].URL || got.Text != links[i].Text || got.IsInternal != links[i].IsInternal {
+			t.Errorf("results[%d] = %+v, does not match link %+v", i, got, links[i])
+		}
+		if got.Accessible != want.accessible {
+			t.Errorf("results[%d].Accessible = %v, want %v", i, got.Accessible, want.accessible)
+		}
+		if got.StatusCode != want.status {
+			t.Errorf("results[%d].StatusCode = %d, want %d", i, got.StatusCode, want.status)
+		}
+		if got.Error != want.errClass {
+			t.Errorf("results[%d].Error = %q, want %q", i, got.Error, want.errClass)
+		}
+		if got.Redirects != want.redirects {
+			t.Errorf("results[%d].Redirects = %d, want %d", i, got.Redirects, want.redirects)
+		}
+	}
+}
+
+func TestCheckLinks_Empty(t *testing.T) {
+	results := CheckLinks(context.Background(), nil, 2)
+	if results == nil || len(results) != 0 {
+		t.Errorf("CheckLinks(nil) = %#v, want empty non-nil slice", results)
+	}
+}
+
+func TestCheckLinks_HeadFallback(t *testing.T) {
+	var gotRange string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			switch r.URL.Path {
+			case "/head-ok":
+				w.WriteHeader(http.StatusOK)
+			case "/no-head-501":
+				w.WriteHeader(http.StatusNotImplemented)
+			case "/no-head-403":
+				w.WriteHeader(http.StatusForbidden)
+			default:
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		gotRange = r.Header.Get("Range")
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/empty":
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+		default:
+			w.Write([]byte("hello world"))
+		}
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		path       string
+		accessible bool
+		method     string
+		status     int
+	}{
+		{path: "/head-ok", accessible: true, method: http.MethodHead, status: http.StatusOK},
+		{path: "/no-head-405", accessible: true, method: http.MethodGet, status: http.StatusOK},
+		{path: "/no-head-501", accessible: true, method: http.MethodGet, status: http.StatusOK},
+		{path: "/no-head-403", accessible: true, method: http.MethodGet, status: http.StatusOK},
+		{path: "/missing", accessible: false, method: http.MethodGet, status: http.StatusNotFound},
+		{path: "/empty", accessible: true, method: http.MethodGet, status: http.StatusRequestedRangeNotSatisfiable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			results := CheckLinks(context.Background(), []Link{{URL: ts.URL + tt.path}}, 1)
+			got := results[0]
+			if got.Accessible != tt.accessible {
+				t.Errorf("Accessible = %v, want %v", got.Accessible, tt.accessible)
+			}
+			if got.Method != tt.method {
+				t.Errorf("Method = %q, want %q", got.Method, tt.method)
+			}
+			if got.StatusCode != tt.status {
+				t.Errorf("StatusCode = %d, want %d", got.StatusCode, tt.status)
+			}
+		})
+	}
+
+	if gotRange != "bytes=0-0" {
+		t.Errorf("GET fallback Range header = %q, want %q", gotRange, "bytes=0-0")
+	}
+}
+
+func TestClassifyLinks_ResourceKinds(t *testing.T) {
+	base := mustParseURL(t, "http://example.com/page")
+	doc := parseHTML(t, `<html><head>
+		<link rel="stylesheet" href="/main.css">
+		<link rel="preload" href="/hero.webp" as="image">
+		<link rel="modulepreload" href="/app.js">
+		<link rel="icon" href="/favicon.ico">
+		<link rel="canonical" href="/page">
+		<script src="https://cdn.example.net/lib.js"></script>
+	</head><body>
+		<a href="/about">About</a>
+		<img src="/logo.png" alt="Logo" srcset="/logo-1x.png 1x, /logo-2x.png 2x">
+		<img src="data:image/png;base64,AAAA">
+		<picture><source srcset="/photo.avif"></picture>
+		<video src="/clip.mp4" poster="/poster.jpg"><source src="/clip.webm"></video>
+		<iframe src="https://maps.example.org/embed" title="Map"></iframe>
+		<form action="/search"><input type="image" src="/go.png" alt="Go"></form>
+	</body></html>`)
+
+	want := []struct {
+		url  string
+		kind ResourceKind
+		text string
+	}{
+		{"http://example.com/main.css", KindStylesheet, ""},
+		{"http://example.com/hero.webp", KindImage, ""},
+		{"http://example.com/app.js", KindScript, ""},
+		{"http://example.com/favicon.ico", KindImage, ""},
+		{"https://cdn.example.net/lib.js", KindScript, ""},
+		{"http://example.com/about", KindAnchor, "About"},
+		{"http://example.com/logo.png", KindImage, "Logo"},
+		{"http://example.com/logo-1x.png", KindImage, "Logo"},
+		{"http://example.com/logo-2x.png", KindImage, "Logo"},
+		{"http://example.com/photo.avif", KindImage, ""},
+		{"http://example.com/clip.mp4", KindMedia, ""},
+		{"http://example.com/poster.jpg", KindImage, ""},
+		{"http://example.com/clip.webm", KindMedia, ""},
+		{"https://maps.example.org/embed", KindFrame, "Map"},
+		{"http://example.com/search", KindForm, ""},
+		{"http://example.com/go.png", KindImage, "Go"},
+	}
+
+	links := ClassifyLinks(doc, base)
+	if len(links) != len(want) {
+		t.Fatalf("got %d links, want %d: %+v", len(links), len(want), links)
+	}
+	for i, w := range want {
+		if links[i].URL != w.url || links[i].Kind != w.kind || links[i].Text != w.text {
+			t.Errorf("links[%d] = %+v, want URL %q kind %q text %q", i, links[i], w.url, w.kind, w.text)
+		}
+	}
+}
+
+func TestParseSrcset(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{in: "a.png", want: []string{"a.png"}},
+		{in: "a.png 1x, b.png 2x", want: []string{"a.png", "b.png"}},
+		{in: " a.png 480w,b.png 800w ", want: []string{"a.png", "b.png"}},
+		{in: "a.png, b.png", want: []string{"a.png", "b.png"}},
+		{in: "img,with,commas.png 1x", want: []string{"img,with,commas.png"}},
+		{in: "", want: nil},
+	}
+	for _, tt := range tests {
+		got := parseSrcset(tt.in)
+		if len(got) != len(tt.want) {
+			t.Errorf("parseSrcset(%q) = %q, want %q", tt.in, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("parseSrcset(%q) = %q, want %q", tt.in, got, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package analyzer
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// minLoginConfidence is the login score a form needs to count as a login
+// form.
+const minLoginConfidence = 0.5
+
+// LoginDetection describes the most likely login form on a page.
+type LoginDetection struct {
+	Path       string   `json:"path"`
+	Confidence float64  `json:"confidence"`
+	Signals    []string `json:"signals"`
+	// IdentifierFirst is set when the form asks for the username or email
+	// only, with the password on a later step.
+	IdentifierFirst bool `json:"identifierFirst"`
+	// OutsideForm is set when the credential fields are not inside a <form>.
+	OutsideForm bool `json:"outsideForm"`
+}
+
+// DetectLogin scores every <form> in doc, and the containers of credential
+// fields that sit outside any form, and returns the most likely login form,
+// or nil if none reaches minLoginConfidence.
+func DetectLogin(doc *html.Node, pageURL *url.URL) *LoginDetection {
+	var best *LoginDetection
+	consider := func(r FormReport, f formFields, outside bool) {
+		if r.Kind != FormLogin || r.Confidence < minLoginConfidence {
+			return
+		}
+		if best != nil && best.Confidence >= r.Confidence {
+			return
+		}
+		best = &LoginDetection{
+			Path:            r.Path,
+			Confidence:      r.Confidence,
+			Signals:         r.Signals,
+			IdentifierFirst: f.passwords == 0 && !hasPasswordName(f.inputs),
+			OutsideForm:     outside,
+		}
+	}
+
+	base := DocumentBase(doc, pageURL)
+	for _, form := range findAll(doc, "form") {
+		r := describeForm(form, pageURL, base)
+		consider(r, collectFormFields(form), false)
+	}
+
+	for _, region := range formlessRegions(doc) {
+		f := collectFormFields(region)
+		r := FormReport{Path: cssPath(region), Action: pageURL.String()}
+		r.Kind, r.Confidence, r.Signals = classifyForm(region, r, f)
+		r.Signals = append(r.Signals, "credential field outside <form>")
+		consider(r, f, true)
+	}
+	return best
+}
+
+// formlessRegions returns, for each password or username field outside a
+// <form>, the nearest ancestor that also holds a button, which is where
+// script-driven logins keep the rest of their controls.
+func formlessRegions(doc *html.Node) []*html.Node {
+	var regions []*html.Node
+	seen := make(map[*html.Node]bool)
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if n.Data == "form" || n.Data == "template" {
+				return
+			}
+			if n.Data == "input" && isCredentialCandidate(n) {
+				if r := controlRegion(n); !seen[r] {
+					seen[r] = true
+					regions = append(regions, r)
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return regions
+}
+
+func isCredentialCandidate(n *html.Node) bool {
+	if _, ok := attr(n, "form"); ok {
+		// Associated with a <form> elsewhere in the document.
+		return false
+	}
+	in := formInput(n)
+	return in.Type == "password" ||
+		hasToken(in.Autocomplete, "username") ||
+		hasToken(in.Autocomplete, "current-password")
+}
+
+func controlRegion(n *html.Node) *html.Node {
+	for p := n.Parent; p != nil && p.Type == html.ElementNode; p = p.Parent {
+		if p.Data == "body" || hasSubmitControl(p) {
+			return p
+		}
+	}
+	return n.Parent
+}
+
+func hasSubmitControl(n *html.Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		if c.Data == "button" || strings.EqualFold(attrValue(c, "role"), "button") {
+			return true
+		}
+		if c.Data == "input" {
+			switch strings.ToLower(attrValue(c, "type")) {
+			case "submit", "button", "image":
+				return true
+			}
+		}
+		if hasSubmitControl(c) {
+			return true
+		}
+	}
+	return false
+}
+
+func findAll(n *html.Node, tag string) []*html.Node {
+	var out []*html.Node
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == tag {
+			out = append(out, n)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return out
+}
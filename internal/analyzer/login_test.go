@@ -0,0 +1,60 @@
+package analyzer
+
+import (
+	"bytes"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestDetectLogin_Corpus(t *testing.T) {
+	tests := []struct {
+		file            string
+		want            bool
+		identifierFirst bool
+		outsideForm     bool
+	}{
+		{file: "github.html", want: true},
+		{file: "wordpress.html", want: true},
+		{file: "shopify.html", want: true},
+		{file: "amazon-identifier.html", want: true, identifierFirst: true},
+		{file: "google-formless.html", want: true, identifierFirst: true, outsideForm: true},
+		{file: "react-formless.html", want: true, outsideForm: true},
+		{file: "newsletter.html", want: false},
+		{file: "signup.html", want: false},
+		{file: "search.html", want: false},
+	}
+
+	page, _ := url.Parse("https://example.com/")
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			raw, err := os.ReadFile(filepath.Join("testdata", "login", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			doc, err := html.Parse(bytes.NewReader(raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := DetectLogin(doc, page)
+
+			if (got != nil) != tt.want {
+				t.Fatalf("DetectLogin() = %+v, want found = %v", got, tt.want)
+			}
+			if got == nil {
+				return
+			}
+			if got.IdentifierFirst != tt.identifierFirst || got.OutsideForm != tt.outsideForm {
+				t.Errorf("identifierFirst = %v, outsideForm = %v, want %v, %v (signals %q)",
+					got.IdentifierFirst, got.OutsideForm, tt.identifierFirst, tt.outsideForm, got.Signals)
+			}
+			if got.Confidence < minLoginConfidence || len(got.Signals) == 0 {
+				t.Errorf("confidence %.2f with signals %q, want at least %.2f", got.Confidence, got.Signals, minLoginConfidence)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package analyzer
+
+import (
+	"mime"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// Metadata holds the SEO-relevant metadata of a page.
+type Metadata struct {
+	Description       string              `json:"description"`
+	Robots            string              `json:"robots"`
+	Canonical         string              `json:"canonical"`
+	Viewport          string              `json:"viewport"`
+	Charset           string              `json:"charset"`
+	Keywords          []string            `json:"keywords"`
+	Hreflang          []HreflangAlternate `json:"hreflang"`
+	TitleLength       LengthCheck         `json:"titleLength"`
+	DescriptionLength LengthCheck         `json:"descriptionLength"`
+}
+
+// HreflangAlternate is a <link rel="alternate" hreflang> entry.
+type HreflangAlternate struct {
+	Lang string `json:"lang"`
+	URL  string `json:"url"`
+}
+
+// Length check statuses.
+const (
+	LengthOK       = "ok"
+	LengthMissing  = "missing"
+	LengthTooShort = "too_short"
+	LengthTooLong  = "too_long"
+)
+
+// LengthCheck compares the length of a text, in characters, against limits.
+type LengthCheck struct {
+	Length int    `json:"length"`
+	Min    int    `json:"min"`
+	Max    int    `json:"max"`
+	Status string `json:"status"`
+}
+
+// MetadataLimits are the recommended title and description lengths, in
+// characters. A zero maximum disables the upper bound.
+type MetadataLimits struct {
+	TitleMin       int `json:"titleMin"`
+	TitleMax       int `json:"titleMax"`
+	DescriptionMin int `json:"descriptionMin"`
+	DescriptionMax int `json:"descriptionMax"`
+}
+
+func DefaultMetadataLimits() MetadataLimits {
+	return MetadataLimits{
+		TitleMin:       30,
+		TitleMax:       60,
+		DescriptionMin: 70,
+		DescriptionMax: 160,
+	}
+}
+
+// ExtractMetadata collects the page metadata from doc. Canonical and
+// hreflang URLs are resolved against the document base.
+func ExtractMetadata(doc *html.Node, pageURL *url.URL, limits MetadataLimits) Metadata {
+	md := Metadata{
+		Keywords: []string{},
+		Hreflang: []HreflangAlternate{},
+	}
+	base := DocumentBase(doc, pageURL)
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "meta":
+				md.addMeta(n)
+			case "link":
+				md.addLink(n, base)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	md.TitleLength = checkLength(strings.TrimSpace(extractTitle(doc)), limits.TitleMin, limits.TitleMax)
+	md.DescriptionLength = checkLength(md.Description, limits.DescriptionMin, limits.DescriptionMax)
+	return md
+}
+
+func (md *Metadata) addMeta(n *html.Node) {
+	if cs, ok := attr(n, "charset"); ok && md.Charset == "" {
+		md.Charset = strings.ToLower(strings.TrimSpace(cs))
+		return
+	}
+
+	content, _ := attr(n, "content")
+	content = strings.TrimSpace(content)
+
+	if equiv, ok := attr(n, "http-equiv"); ok {
+		if strings.EqualFold(equiv, "content-type") && md.Charset == "" {
+			if _, params, err := mime.ParseMediaType(content); err == nil {
+				md.Charset = strings.ToLower(params["charset"])
+			}
+		}
+		return
+	}
+
+	name, _ := attr(n, "name")
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "description":
+		setOnce(&md.Description, content)
+	case "robots":
+		setOnce(&md.Robots, content)
+	case "viewport":
+		setOnce(&md.Viewport, content)
+	case "keywords":
+		if len(md.Keywords) > 0 {
+			return
+		}
+		for _, k := range strings.Split(content, ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				md.Keywords = append(md.Keywords, k)
+			}
+		}
+	}
+}
+
+func (md *Metadata) addLink(n *html.Node, base *url.URL) {
+	rel, _ := attr(n, "rel")
+	href, ok := attr(n, "href")
+	if !ok {
+		return
+	}
+
+	for _, r := range strings.Fields(strings.ToLower(rel)) {
+		switch r {
+		case "canonical":
+			setOnce(&md.Canonical, resolveRef(base, href))
+		case "alternate":
+			if lang, ok := attr(n, "hreflang"); ok {
+				md.Hreflang = append(md.Hreflang, HreflangAlternate{
+					Lang: strings.TrimSpace(lang),
+					URL:  resolveRef(base, href),
+				})
+			}
+		}
+	}
+}
+
+func setOnce(dst *string, v string) {
+	if *dst == "" {
+		*dst = v
+	}
+}
+
+// resolveRef resolves ref against base, returning ref unchanged when it does
+// not parse.
+func resolveRef(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return base.ResolveReference(u).String()
+}
+
+func checkLength(s string, lo, hi int) LengthCheck {
+	c := LengthCheck{Length: utf8.RuneCountInString(s), Min: lo, Max: hi}
+	switch {
+	case c.Length == 0:
+		c.Status = LengthMissing
+	case c.Length < lo:
+		c.Status = LengthTooShort
+	case hi > 0 && c.Length > hi:
+		c.Status = LengthTooLong
+	default:
+		c.Status = LengthOK
+	}
+	return c
+}
@@ -0,0 +1,111 @@
+package analyzer
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestExtractMetadata(t *testing.T) {
+	page := mustParseURL(t, "https://example.com/shop/sofa")
+	doc := parseHTML(t, `<!DOCTYPE html><html><head>
+		<meta charset="UTF-8">
+		<title>Grey three-seater sofa with storage | Example Shop</title>
+		<meta name="Description" content="  Our best-selling three-seater sofa in grey fabric, with hidden storage under every seat cushion.  ">
+		<meta name="robots" content="index, follow">
+		<meta name="viewport" content="width=device-width, initial-scale=1">
+		<meta name="keywords" content="sofa, grey sofa, , storage">
+		<meta name="description" content="second description is ignored">
+		<link rel="canonical" href="/shop/sofa">
+		<link rel="alternate" hreflang="de" href="https://example.de/shop/sofa">
+		<link rel="alternate" hreflang="x-default" href="/shop/sofa">
+		<link rel="alternate" type="application/rss+xml" href="/feed">
+	</head><body></body></html>`)
+
+	md := ExtractMetadata(doc, page, DefaultMetadataLimits())
+
+	if md.Charset != "utf-8" {
+		t.Errorf("Charset = %q, want %q", md.Charset, "utf-8")
+	}
+	if !strings.HasPrefix(md.Description, "Our best-selling") || strings.HasSuffix(md.Description, " ") {
+		t.Errorf("Description = %q, want trimmed first description", md.Description)
+	}
+	if md.Robots != "index, follow" {
+		t.Errorf("Robots = %q, want %q", md.Robots, "index, follow")
+	}
+	if md.Viewport != "width=device-width, initial-scale=1" {
+		t.Errorf("Viewport = %q", md.Viewport)
+	}
+	if md.Canonical != "https://example.com/shop/sofa" {
+		t.Errorf("Canonical = %q, want %q", md.Canonical, "https://example.com/shop/sofa")
+	}
+	wantKeywords := []string{"sofa", "grey sofa", "storage"}
+	if strings.Join(md.Keywords, "|") != strings.Join(wantKeywords, "|") {
+		t.Errorf("Keywords = %q, want %q", md.Keywords, wantKeywords)
+	}
+	wantHreflang := []HreflangAlternate{
+		{Lang: "de", URL: "https://example.de/shop/sofa"},
+		{Lang: "x-default", URL: "https://example.com/shop/sofa"},
+	}
+	if len(md.Hreflang) != len(wantHreflang) {
+		t.Fatalf("Hreflang = %+v, want %+v", md.Hreflang, wantHreflang)
+	}
+	for i := range wantHreflang {
+		if md.Hreflang[i] != wantHreflang[i] {
+			t.Errorf("Hreflang[%d] = %+v, want %+v", i, md.Hreflang[i], wantHreflang[i])
+		}
+	}
+	if md.TitleLength.Status != LengthOK || md.TitleLength.Length != 50 {
+		t.Errorf("TitleLength = %+v, want ok with length 50", md.TitleLength)
+	}
+	if md.DescriptionLength.Status != LengthOK {
+		t.Errorf("DescriptionLength = %+v, want ok", md.DescriptionLength)
+	}
+}
+
+func TestExtractMetadata_HTTPEquivCharset(t *testing.T) {
+	doc := parseHTML(t, `<html><head><meta http-equiv="Content-Type" content="text/html; charset=ISO-8859-1"></head></html>`)
+	md := ExtractMetadata(doc, mustParseURL(t, "http://example.com/"), DefaultMetadataLimits())
+	if md.Charset != "iso-8859-1" {
+		t.Errorf("Charset = %q, want %q", md.Charset, "iso-8859-1")
+	}
+}
+
+func TestCheckLength(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		lo, hi int
+		want   string
+	}{
+		{name: "missing", text: "", lo: 1, hi: 10, want: LengthMissing},
+		{name: "too short", text: "abc", lo: 5, hi: 10, want: LengthTooShort},
+		{name: "too long", text: "abcdefghijk", lo: 1, hi: 10, want: LengthTooLong},
+		{name: "within limits", text: "abcde", lo: 5, hi: 5, want: LengthOK},
+		{name: "no upper bound", text: "abcdefghijk", lo: 1, hi: 0, want: LengthOK},
+		{name: "counts characters not bytes", text: "ääääää", lo: 1, hi: 6, want: LengthOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkLength(tt.text, tt.lo, tt.hi); got.Status != tt.want {
+				t.Errorf("checkLength(%q, %d, %d) = %+v, want status %q", tt.text, tt.lo, tt.hi, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyze_MetadataLimits(t *testing.T) {
+	rawHTML := []byte(`<html><head><title>Short</title></head></html>`)
+	limits := MetadataLimits{TitleMin: 1, TitleMax: 4}
+	resp, err := Analyze(context.Background(), rawHTML, "http://example.com", WithMetadataLimits(limits))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := LengthCheck{Length: 5, Min: 1, Max: 4, Status: LengthTooLong}
+	if resp.Metadata.TitleLength != want {
+		t.Errorf("TitleLength = %+v, want %+v", resp.Metadata.TitleLength, want)
+	}
+	if resp.Metadata.DescriptionLength.Status != LengthMissing {
+		t.Errorf("DescriptionLength.Status = %q, want %q", resp.Metadata.DescriptionLength.Status, LengthMissing)
+	}
+}
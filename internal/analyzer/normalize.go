@@ -0,0 +1,108 @@
+package analyzer
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// NormalizeOptions controls the optional steps of URL normalization. Fragments
+// and empty queries are always stripped, scheme and host lowercased and
+// default ports removed.
+type NormalizeOptions struct {
+	// StripTrailingSlash treats /a/ and /a as the same URL.
+	StripTrailingSlash bool `json:"stripTrailingSlash"`
+	// SortQuery treats ?a=1&b=2 and ?b=2&a=1 as the same URL.
+	SortQuery bool `json:"sortQuery"`
+}
+
+// NormalizeURL returns the canonical form of rawURL used to detect duplicate
+// links. Unparseable URLs are returned unchanged.
+func NormalizeURL(rawURL string, opts NormalizeOptions) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	u.Fragment = ""
+	u.RawFragment = ""
+	u.ForceQuery = false
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if p := u.Port(); p != "" && p == defaultPort(u.Scheme) {
+		u.Host = u.Hostname()
+		if strings.Contains(u.Host, ":") {
+			u.Host = "[" + u.Host + "]"
+		}
+	}
+
+	if u.Host != "" && u.Path == "" {
+		u.Path = "/"
+		u.RawPath = ""
+	}
+	if opts.StripTrailingSlash && len(u.Path) > 1 {
+		u.Path = strings.TrimRight(u.Path, "/")
+		u.RawPath = ""
+		if u.Path == "" {
+			u.Path = "/"
+		}
+	}
+	if opts.SortQuery && u.RawQuery != "" {
+		u.RawQuery = sortedQuery(u.RawQuery)
+	}
+
+	return u.String()
+}
+
+func defaultPort(scheme string) string {
+	switch scheme {
+	case "http":
+		return "80"
+	case "https":
+		return "443"
+	}
+	return ""
+}
+
+// sortedQuery orders the parameters of a raw query by key, keeping the
+// relative order of repeated keys.
+func sortedQuery(raw string) string {
+	params := strings.Split(raw, "&")
+	sort.SliceStable(params, func(i, j int) bool {
+		ki, _, _ := strings.Cut(params[i], "=")
+		kj, _, _ := strings.Cut(params[j], "=")
+		return ki < kj
+	})
+	return strings.Join(params, "&")
+}
+
+// DedupeLinks collapses links with the same kind and normalized URL into one
+// entry, in order of first appearance. The surviving link keeps the URL it
+// was first seen with, which is the one checked, and carries the first
+// non-empty text and the number of occurrences.
+func DedupeLinks(links []Link, opts NormalizeOptions) []Link {
+	type key struct {
+		url  string
+		kind ResourceKind
+	}
+
+	var unique []Link
+	index := make(map[key]int)
+	for _, l := range links {
+		k := key{NormalizeURL(l.URL, opts), l.Kind}
+
+		i, ok := index[k]
+		if !ok {
+			l.Occurrences = max(l.Occurrences, 1)
+			index[k] = len(unique)
+			unique = append(unique, l)
+			continue
+		}
+
+		unique[i].Occurrences += max(l.Occurrences, 1)
+		if unique[i].Text == "" {
+			unique[i].Text = l.Text
+		}
+	}
+	return unique
+}
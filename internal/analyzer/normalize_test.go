@@ -0,0 +1,58 @@
+package analyzer
+
+import "testing"
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		opts NormalizeOptions
+		want string
+	}{
+		{name: "fragment stripped", in: "http://example.com/a#top", want: "http://example.com/a"},
+		{name: "empty query stripped", in: "http://example.com/a?", want: "http://example.com/a"},
+		{name: "scheme and host lowercased", in: "HTTP://Example.COM/Path", want: "http://example.com/Path"},
+		{name: "default http port removed", in: "http://example.com:80/a", want: "http://example.com/a"},
+		{name: "default https port removed", in: "https://example.com:443/a", want: "https://example.com/a"},
+		{name: "non-default port kept", in: "http://example.com:8080/a", want: "http://example.com:8080/a"},
+		{name: "empty path becomes slash", in: "http://example.com", want: "http://example.com/"},
+		{name: "trailing slash kept by default", in: "http://example.com/a/", want: "http://example.com/a/"},
+		{name: "trailing slash stripped", in: "http://example.com/a/", opts: NormalizeOptions{StripTrailingSlash: true}, want: "http://example.com/a"},
+		{name: "root slash kept", in: "http://example.com/", opts: NormalizeOptions{StripTrailingSlash: true}, want: "http://example.com/"},
+		{name: "query order kept by default", in: "http://example.com/?b=2&a=1", want: "http://example.com/?b=2&a=1"},
+		{name: "query sorted", in: "http://example.com/?b=2&a=1&b=1", opts: NormalizeOptions{SortQuery: true}, want: "http://example.com/?a=1&b=2&b=1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeURL(tt.in, tt.opts); got != tt.want {
+				t.Errorf("NormalizeURL(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDedupeLinks(t *testing.T) {
+	links := []Link{
+		{URL: "http://example.com/cart", Kind: KindAnchor, Occurrences: 1},
+		{URL: "http://example.com/about", Text: "About", Kind: KindAnchor, Occurrences: 1},
+		{URL: "http://EXAMPLE.com/cart#footer", Text: "Cart", Kind: KindAnchor, Occurrences: 1},
+		{URL: "http://example.com/cart", Kind: KindImage, Occurrences: 1},
+		{URL: "http://example.com:80/cart", Text: "Basket", Kind: KindAnchor, Occurrences: 1},
+	}
+
+	got := DedupeLinks(links, NormalizeOptions{})
+	want := []Link{
+		{URL: "http://example.com/cart", Text: "Cart", Kind: KindAnchor, Occurrences: 3},
+		{URL: "http://example.com/about", Text: "About", Kind: KindAnchor, Occurrences: 1},
+		{URL: "http://example.com/cart", Kind: KindImage, Occurrences: 1},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d links, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("links[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
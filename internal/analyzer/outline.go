@@ -0,0 +1,123 @@
+package analyzer
+
+import (
+	"fmt"
+
+	"golang.org/x/net/html"
+)
+
+// Outline issue types.
+const (
+	OutlineNoH1         = "no_h1"
+	OutlineMultipleH1   = "multiple_h1"
+	OutlineSkippedLevel = "skipped_level"
+	OutlineEmpty        = "empty_heading"
+	OutlineHidden       = "hidden_heading"
+)
+
+// HeadingNode is a heading in the document outline. Children are the
+// headings of a deeper level that follow it before the next heading of the
+// same or a higher level.
+type HeadingNode struct {
+	Level    int            `json:"level"`
+	Text     string         `json:"text"`
+	Children []*HeadingNode `json:"children"`
+}
+
+// OutlineIssue is a structural problem in the heading hierarchy.
+type OutlineIssue struct {
+	Type    string `json:"type"`
+	Level   int    `json:"level,omitempty"`
+	Text    string `json:"text,omitempty"`
+	Message string `json:"message"`
+}
+
+// HeadingOutline is the heading tree of a document and the problems found
+// while building it.
+type HeadingOutline struct {
+	Headings []*HeadingNode `json:"headings"`
+	Issues   []OutlineIssue `json:"issues"`
+}
+
+// BuildOutline builds the heading tree of doc in document order. Headings
+// inside <template>, hidden or aria-hidden subtrees are left out of the tree
+// and reported as issues.
+func BuildOutline(doc *html.Node) HeadingOutline {
+	out := HeadingOutline{Headings: []*HeadingNode{}, Issues: []OutlineIssue{}}
+
+	var stack []*HeadingNode
+	prev, h1s := 0, 0
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && headingTags[n.Data] {
+			level := int(n.Data[1] - '0')
+			h := &HeadingNode{Level: level, Text: headingText(n), Children: []*HeadingNode{}}
+			if isHidden(n) {
+				out.Issues = append(out.Issues, OutlineIssue{
+					Type:    OutlineHidden,
+					Level:   level,
+					Text:    h.Text,
+					Message: fmt.Sprintf("h%d is hidden from assistive technology", level),
+				})
+				return
+			}
+
+			if level == 1 {
+				h1s++
+			}
+			if h.Text == "" {
+				out.Issues = append(out.Issues, OutlineIssue{
+					Type:    OutlineEmpty,
+					Level:   level,
+					Message: fmt.Sprintf("h%d has no text", level),
+				})
+			}
+			if prev > 0 && level > prev+1 {
+				out.Issues = append(out.Issues, OutlineIssue{
+					Type:    OutlineSkippedLevel,
+					Level:   level,
+					Text:    h.Text,
+					Message: fmt.Sprintf("h%d follows h%d, skipping a level", level, prev),
+				})
+			}
+			prev = level
+
+			for len(stack) > 0 && stack[len(stack)-1].Level >= level {
+				stack = stack[:len(stack)-1]
+			}
+			if len(stack) == 0 {
+				out.Headings = append(out.Headings, h)
+			} else {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, h)
+			}
+			stack = append(stack, h)
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	switch {
+	case h1s == 0:
+		out.Issues = append(out.Issues, OutlineIssue{Type: OutlineNoH1, Message: "page has no h1"})
+	case h1s > 1:
+		out.Issues = append(out.Issues, OutlineIssue{
+			Type:    OutlineMultipleH1,
+			Level:   1,
+			Message: fmt.Sprintf("page has %d h1 elements", h1s),
+		})
+	}
+	return out
+}
+
+// headingText returns the text of a heading, falling back to the alt text of
+// images it contains.
+func headingText(n *html.Node) string {
+	if text := innerText(n); text != "" {
+		return text
+	}
+	return imageAltText(n)
+}
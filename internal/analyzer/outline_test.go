@@ -0,0 +1,63 @@
+package analyzer
+
+import "testing"
+
+func TestBuildOutline(t *testing.T) {
+	doc := parseHTML(t, `<html><body>
+		<h1>Shop <small>online</small></h1>
+		<h2>Sofas</h2>
+		<h4>Grey sofa</h4>
+		<h3>Leather sofas</h3>
+		<h2><img src="/logo.png" alt="Lamps"></h2>
+		<h3></h3>
+		<template><h2>Template</h2></template>
+		<div aria-hidden="true"><h2>Hidden</h2></div>
+		<h1>Second h1</h1>
+	</body></html>`)
+
+	got := BuildOutline(doc)
+
+	if len(got.Headings) != 2 {
+		t.Fatalf("got %d root headings, want 2: %+v", len(got.Headings), got.Headings)
+	}
+	root := got.Headings[0]
+	if root.Text != "Shop online" || len(root.Children) != 2 {
+		t.Fatalf("root = %q with %d children, want \"Shop online\" with 2", root.Text, len(root.Children))
+	}
+	sofas := root.Children[0]
+	if sofas.Text != "Sofas" || len(sofas.Children) != 2 {
+		t.Errorf("Sofas has %d children, want 2 (h4 and h3)", len(sofas.Children))
+	}
+	if lamps := root.Children[1]; lamps.Text != "Lamps" || len(lamps.Children) != 1 {
+		t.Errorf("children[1] = %q with %d children, want Lamps with 1", lamps.Text, len(lamps.Children))
+	}
+
+	want := []string{
+		OutlineSkippedLevel, // h2 -> h4
+		OutlineEmpty,        // empty h3
+		OutlineHidden,       // <template>
+		OutlineHidden,       // aria-hidden
+		OutlineMultipleH1,
+	}
+	if len(got.Issues) != len(want) {
+		t.Fatalf("got issues %+v, want types %v", got.Issues, want)
+	}
+	for i, typ := range want {
+		if got.Issues[i].Type != typ {
+			t.Errorf("issues[%d].Type = %q, want %q", i, got.Issues[i].Type, typ)
+		}
+	}
+}
+
+func TestBuildOutline_NoH1(t *testing.T) {
+	doc := parseHTML(t, `<html><body><h2>Only</h2><h3>Sub</h3></body></html>`)
+
+	got := BuildOutline(doc)
+
+	if len(got.Issues) != 1 || got.Issues[0].Type != OutlineNoH1 {
+		t.Errorf("Issues = %+v, want only %s", got.Issues, OutlineNoH1)
+	}
+	if len(got.Headings) != 1 || len(got.Headings[0].Children) != 1 {
+		t.Errorf("Headings = %+v, want h2 with one child", got.Headings)
+	}
+}
@@ -0,0 +1,38 @@
+package analyzer
+
+// Phase names a step of an analysis.
+type Phase string
+
+const (
+	// PhaseParsed is reported once the document has been parsed.
+	PhaseParsed Phase = "parsed"
+	// PhaseStatic is reported with every result that needs no network
+	// access, before any link is checked.
+	PhaseStatic Phase = "static"
+	// PhaseLinks is reported after each distinct URL has been checked.
+	PhaseLinks Phase = "links"
+)
+
+// Progress reports how far an analysis has got.
+type Progress struct {
+	Phase Phase `json:"phase"`
+	// Checked and Total count the distinct URLs checked so far and overall
+	// in PhaseLinks.
+	Checked int `json:"checked,omitempty"`
+	Total   int `json:"total,omitempty"`
+	// Result holds the partial response in PhaseStatic: links, resources
+	// and link counts are still empty.
+	Result *AnalyzeResponse `json:"result,omitempty"`
+}
+
+// WithProgress makes Analyze report its progress to fn. Calls to fn are
+// never concurrent, and Analyze waits for each to return.
+func WithProgress(fn func(Progress)) Option {
+	return func(o *options) { o.progress = fn }
+}
+
+func (o *options) report(p Progress) {
+	if o.progress != nil {
+		o.progress(p)
+	}
+}
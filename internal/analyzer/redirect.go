@@ -0,0 +1,146 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+const (
+	maxRedirects = 10
+
+	// DefaultLongRedirectChain is the number of hops above which a redirect
+	// chain is flagged as too long.
+	DefaultLongRedirectChain = 3
+)
+
+// Errors returned by CheckRedirect, wrapped in the *url.Error of the request.
+var (
+	ErrTooManyRedirects = errors.New("too many redirects")
+	ErrRedirectLoop     = errors.New("redirect loop")
+)
+
+// RedirectHop is one redirect response in a chain.
+type RedirectHop struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"statusCode"`
+	Location   string `json:"location"`
+}
+
+// RedirectChain describes the redirects followed for a single request.
+type RedirectChain struct {
+	Hops []RedirectHop `json:"hops"`
+	// Loop is set when a redirect pointed back at a URL already visited.
+	Loop bool `json:"loop"`
+	// Downgrade is set when any hop went from https to http.
+	Downgrade bool `json:"downgrade"`
+	// TooLong is set when the chain has more hops than the threshold.
+	TooLong bool `json:"tooLong"`
+}
+
+// RedirectTrace collects the hops CheckRedirect sees for requests made with
+// a context returned by TraceRedirects.
+type RedirectTrace struct {
+	mu   sync.Mutex
+	hops []RedirectHop
+	loop bool
+}
+
+type redirectTraceKey struct{}
+
+// TraceRedirects returns a context that records the redirects of requests
+// made with it into the returned trace. The client must use CheckRedirect.
+func TraceRedirects(ctx context.Context) (context.Context, *RedirectTrace) {
+	t := &RedirectTrace{}
+	return context.WithValue(ctx, redirectTraceKey{}, t), t
+}
+
+func redirectTraceFrom(ctx context.Context) *RedirectTrace {
+	t, _ := ctx.Value(redirectTraceKey{}).(*RedirectTrace)
+	return t
+}
+
+// CheckRedirect is an http.Client CheckRedirect policy that follows up to 10
+// redirects, stops on loops and records hops into the request's trace.
+func CheckRedirect(req *http.Request, via []*http.Request) error {
+	t := redirectTraceFrom(req.Context())
+	if t != nil && req.Response != nil {
+		t.add(RedirectHop{
+			URL:        via[len(via)-1].URL.String(),
+			StatusCode: req.Response.StatusCode,
+			Location:   req.Response.Header.Get("Location"),
+		})
+	}
+
+	next := req.URL.String()
+	for _, v := range via {
+		if v.URL.String() == next {
+			if t != nil {
+				t.setLoop()
+			}
+			return ErrRedirectLoop
+		}
+	}
+
+	if len(via) >= maxRedirects {
+		return ErrTooManyRedirects
+	}
+	return nil
+}
+
+func (t *RedirectTrace) add(h RedirectHop) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.hops = append(t.hops, h)
+}
+
+func (t *RedirectTrace) setLoop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.loop = true
+}
+
+func (t *RedirectTrace) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.hops = nil
+	t.loop = false
+}
+
+// Chain returns the recorded hops, flagging chains with more than longChain
+// hops as too long. A longChain of zero means DefaultLongRedirectChain.
+func (t *RedirectTrace) Chain(longChain int) RedirectChain {
+	if longChain <= 0 {
+		longChain = DefaultLongRedirectChain
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c := RedirectChain{
+		Hops:    append([]RedirectHop(nil), t.hops...),
+		Loop:    t.loop,
+		TooLong: len(t.hops) > longChain,
+	}
+	for _, h := range c.Hops {
+		if isDowngrade(h) {
+			c.Downgrade = true
+			break
+		}
+	}
+	return c
+}
+
+func isDowngrade(h RedirectHop) bool {
+	from, err := url.Parse(h.URL)
+	if err != nil || from.Scheme != "https" {
+		return false
+	}
+	to, err := url.Parse(h.Location)
+	if err != nil {
+		return false
+	}
+	return from.ResolveReference(to).Scheme == "http"
+}
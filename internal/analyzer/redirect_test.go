@@ -0,0 +1,99 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLinkChecker_RedirectChain(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+		case "/one":
+			http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+		case "/loop-a":
+			http.Redirect(w, r, "/loop-b", http.StatusFound)
+		case "/loop-b":
+			http.Redirect(w, r, "/loop-a", http.StatusFound)
+		default:
+			// /hop/N redirects to /hop/N-1 and /hop/0 to /ok.
+			var n int
+			if _, err := fmt.Sscanf(r.URL.Path, "/hop/%d", &n); err != nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			next := "/ok"
+			if n > 0 {
+				next = fmt.Sprintf("/hop/%d", n-1)
+			}
+			http.Redirect(w, r, next, http.StatusFound)
+		}
+	}))
+	defer ts.Close()
+
+	c := NewLinkChecker()
+	c.LongRedirectChain = 3
+	results := c.Check(context.Background(), []Link{
+		{URL: ts.URL + "/ok"},
+		{URL: ts.URL + "/one"},
+		{URL: ts.URL + "/hop/3"},
+		{URL: ts.URL + "/loop-a"},
+	})
+
+	if results[0].RedirectChain != nil {
+		t.Errorf("/ok RedirectChain = %+v, want nil", results[0].RedirectChain)
+	}
+
+	one := results[1].RedirectChain
+	if one == nil || len(one.Hops) != 1 {
+		t.Fatalf("/one RedirectChain = %+v, want 1 hop", one)
+	}
+	want := RedirectHop{URL: ts.URL + "/one", StatusCode: http.StatusMovedPermanently, Location: "/ok"}
+	if one.Hops[0] != want {
+		t.Errorf("/one hop = %+v, want %+v", one.Hops[0], want)
+	}
+	if one.TooLong || one.Loop || one.Downgrade {
+		t.Errorf("/one flags = %+v, want none set", one)
+	}
+
+	long := results[2].RedirectChain
+	if long == nil || len(long.Hops) != 4 || !long.TooLong {
+		t.Errorf("/hop/3 RedirectChain = %+v, want 4 hops flagged too long", long)
+	}
+	if !results[2].Accessible || results[2].Redirects != 4 {
+		t.Errorf("/hop/3 = %+v, want accessible after 4 redirects", results[2])
+	}
+
+	loop := results[3]
+	if loop.Accessible || loop.Error != ErrorLoop {
+		t.Errorf("/loop-a = %+v, want inaccessible with error %q", loop, ErrorLoop)
+	}
+	if loop.RedirectChain == nil || !loop.RedirectChain.Loop || len(loop.RedirectChain.Hops) != 2 {
+		t.Errorf("/loop-a RedirectChain = %+v, want 2 hops flagged as loop", loop.RedirectChain)
+	}
+}
+
+func TestRedirectTrace_Downgrade(t *testing.T) {
+	tests := []struct {
+		name string
+		hop  RedirectHop
+		want bool
+	}{
+		{name: "https to http", hop: RedirectHop{URL: "https://example.com/a", Location: "http://example.com/b"}, want: true},
+		{name: "https to https", hop: RedirectHop{URL: "https://example.com/a", Location: "https://example.com/b"}, want: false},
+		{name: "https to relative", hop: RedirectHop{URL: "https://example.com/a", Location: "/b"}, want: false},
+		{name: "http to https", hop: RedirectHop{URL: "http://example.com/a", Location: "https://example.com/b"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trace := &RedirectTrace{hops: []RedirectHop{tt.hop}}
+			if got := trace.Chain(DefaultLongRedirectChain).Downgrade; got != tt.want {
+				t.Errorf("Downgrade = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package analyzer
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// ScopeMode selects how internal links are told apart from external ones.
+type ScopeMode string
+
+const (
+	// ScopeHost treats a link as internal when it has the page's host and
+	// port, with default ports normalized.
+	ScopeHost ScopeMode = "host"
+	// ScopeDomain treats a link as internal when it shares the page's
+	// registrable domain (eTLD+1), so www.example.com, shop.example.com and
+	// example.com are all internal to each other.
+	ScopeDomain ScopeMode = "domain"
+)
+
+// Scope is the policy deciding which links are internal to a page.
+type Scope struct {
+	Mode ScopeMode `json:"mode"`
+	// Aliases are extra hosts that always count as internal. An entry of the
+	// form "*.example.com" matches every subdomain of example.com.
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// Normalize fills in defaults and canonicalizes aliases. It returns an error
+// for an unknown mode.
+func (s Scope) Normalize() (Scope, error) {
+	switch s.Mode {
+	case "":
+		s.Mode = ScopeHost
+	case ScopeHost, ScopeDomain:
+	default:
+		return Scope{}, fmt.Errorf("unknown scope mode %q", s.Mode)
+	}
+
+	aliases := make([]string, 0, len(s.Aliases))
+	for _, a := range s.Aliases {
+		if a = canonicalHost(a); a != "" {
+			aliases = append(aliases, a)
+		}
+	}
+	s.Aliases = aliases
+	return s, nil
+}
+
+// Contains reports whether u is internal to page under the scope.
+func (s Scope) Contains(page, u *url.URL) bool {
+	host := canonicalHost(u.Hostname())
+	if host == "" {
+		return false
+	}
+
+	for _, a := range s.Aliases {
+		if suffix, ok := strings.CutPrefix(a, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+		} else if host == a {
+			return true
+		}
+	}
+
+	pageHost := canonicalHost(page.Hostname())
+	switch s.Mode {
+	case ScopeDomain:
+		return registrableDomain(host) == registrableDomain(pageHost)
+	default:
+		return host == pageHost && explicitPort(u) == explicitPort(page)
+	}
+}
+
+func canonicalHost(h string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(h)), ".")
+}
+
+// registrableDomain returns the eTLD+1 of host, or host itself for IP
+// addresses and names without a public suffix such as "localhost".
+func registrableDomain(host string) string {
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	d, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return d
+}
+
+// explicitPort returns the port of u unless it is the default port of its
+// scheme, so http://example.com/ and https://example.com:443/ are on the same
+// host while http://example.com:8080/ is not.
+func explicitPort(u *url.URL) string {
+	if p := u.Port(); p != defaultPort(strings.ToLower(u.Scheme)) {
+		return p
+	}
+	return ""
+}
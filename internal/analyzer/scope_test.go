@@ -0,0 +1,69 @@
+package analyzer
+
+import "testing"
+
+func TestScope_Contains(t *testing.T) {
+	page := mustParseURL(t, "https://www.example.com/page")
+
+	tests := []struct {
+		name  string
+		scope Scope
+		url   string
+		want  bool
+	}{
+		{name: "host: same host", scope: Scope{Mode: ScopeHost}, url: "https://www.example.com/a", want: true},
+		{name: "host: case and trailing dot", scope: Scope{Mode: ScopeHost}, url: "https://WWW.Example.com./a", want: true},
+		{name: "host: explicit default port", scope: Scope{Mode: ScopeHost}, url: "https://www.example.com:443/a", want: true},
+		{name: "host: http on https page", scope: Scope{Mode: ScopeHost}, url: "http://www.example.com/a", want: true},
+		{name: "host: http default port", scope: Scope{Mode: ScopeHost}, url: "http://www.example.com:80/a", want: true},
+		{name: "host: other port", scope: Scope{Mode: ScopeHost}, url: "https://www.example.com:8443/a", want: false},
+		{name: "host: apex domain", scope: Scope{Mode: ScopeHost}, url: "https://example.com/a", want: false},
+		{name: "domain: apex domain", scope: Scope{Mode: ScopeDomain}, url: "https://example.com/a", want: true},
+		{name: "domain: subdomain", scope: Scope{Mode: ScopeDomain}, url: "http://shop.example.com:8080/a", want: true},
+		{name: "domain: other domain", scope: Scope{Mode: ScopeDomain}, url: "https://example.org/a", want: false},
+		{name: "alias: exact", scope: Scope{Mode: ScopeHost, Aliases: []string{"cdn.example.net"}}, url: "https://cdn.example.net/x.png", want: true},
+		{name: "alias: wildcard", scope: Scope{Mode: ScopeHost, Aliases: []string{"*.example.net"}}, url: "https://img.eu.example.net/x.png", want: true},
+		{name: "alias: wildcard excludes apex", scope: Scope{Mode: ScopeHost, Aliases: []string{"*.example.net"}}, url: "https://example.net/x.png", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scope, err := tt.scope.Normalize()
+			if err != nil {
+				t.Fatalf("Normalize() error: %v", err)
+			}
+			if got := scope.Contains(page, mustParseURL(t, tt.url)); got != tt.want {
+				t.Errorf("Contains(%q) = %v, want %v", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScope_DomainPublicSuffix(t *testing.T) {
+	page := mustParseURL(t, "https://shop.example.co.uk/")
+	scope := Scope{Mode: ScopeDomain}
+
+	if !scope.Contains(page, mustParseURL(t, "https://www.example.co.uk/")) {
+		t.Error("www.example.co.uk should share the registrable domain of shop.example.co.uk")
+	}
+	if scope.Contains(page, mustParseURL(t, "https://other.co.uk/")) {
+		t.Error("other.co.uk should not share a registrable domain with example.co.uk")
+	}
+}
+
+func TestScope_Normalize(t *testing.T) {
+	s, err := Scope{Aliases: []string{" CDN.Example.net. ", ""}}.Normalize()
+	if err != nil {
+		t.Fatalf("Normalize() error: %v", err)
+	}
+	if s.Mode != ScopeHost {
+		t.Errorf("Mode = %q, want %q", s.Mode, ScopeHost)
+	}
+	if len(s.Aliases) != 1 || s.Aliases[0] != "cdn.example.net" {
+		t.Errorf("Aliases = %q, want [cdn.example.net]", s.Aliases)
+	}
+
+	if _, err := (Scope{Mode: "galaxy"}).Normalize(); err == nil {
+		t.Error("Normalize() with unknown mode: expected error, got nil")
+	}
+}
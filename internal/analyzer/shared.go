@@ -0,0 +1,96 @@
+package analyzer
+
+import (
+	"context"
+	"sync"
+)
+
+// CacheStats counts the work done by a shared LinkChecker.
+type CacheStats struct {
+	// URLs is the number of distinct URLs requested.
+	URLs int `json:"urls"`
+	// Hits is the number of checks answered from the cache instead.
+	Hits int `json:"hits"`
+}
+
+// sharedChecks is the state common to all checks of a shared LinkChecker.
+type sharedChecks struct {
+	sem chan struct{}
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	hits    int
+}
+
+// cacheEntry is the result for one URL; done is closed once it is set.
+type cacheEntry struct {
+	done   chan struct{}
+	result LinkResult
+}
+
+// Shared returns a copy of c whose checks share, across every call to
+// Check, one budget of Workers concurrent requests and a cache of results by
+// URL. Pages analyzed with it together request each URL once; the per-host
+// limits are those of c, which all its checks share anyway.
+func (c *LinkChecker) Shared() *LinkChecker {
+	s := *c
+	s.shared = &sharedChecks{
+		sem:     make(chan struct{}, max(c.Workers, 1)),
+		entries: make(map[string]*cacheEntry),
+	}
+	return &s
+}
+
+// CacheStats returns the cache counters of a checker returned by Shared,
+// and zero counters for any other.
+func (c *LinkChecker) CacheStats() CacheStats {
+	if c.shared == nil {
+		return CacheStats{}
+	}
+	c.shared.mu.Lock()
+	defer c.shared.mu.Unlock()
+	return CacheStats{URLs: len(c.shared.entries), Hits: c.shared.hits}
+}
+
+// claim returns the cache entry for url and whether the caller is the first
+// to ask for it and must check the URL and fill the entry.
+func (s *sharedChecks) claim(url string) (*cacheEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[url]; ok {
+		return e, false
+	}
+	e := &cacheEntry{done: make(chan struct{})}
+	s.entries[url] = e
+	return e, true
+}
+
+// fill sets the result of e. Canceled checks are not cached, so the next
+// page to ask for the URL, or one already waiting, checks it again.
+func (s *sharedChecks) fill(url string, e *cacheEntry, r LinkResult) {
+	if r.Error == ErrorCanceled {
+		s.mu.Lock()
+		delete(s.entries, url)
+		s.mu.Unlock()
+	}
+	e.result = r
+	close(e.done)
+}
+
+// wait returns the result of e for l once it is set, or a canceled result if
+// ctx is done first. It reports false when the check filling e was canceled
+// while ctx was not, so the caller should claim the URL again.
+func (s *sharedChecks) wait(ctx context.Context, e *cacheEntry, l Link) (LinkResult, bool) {
+	select {
+	case <-e.done:
+		if e.result.Error == ErrorCanceled && ctx.Err() == nil {
+			return LinkResult{}, false
+		}
+		s.mu.Lock()
+		s.hits++
+		s.mu.Unlock()
+		return e.result.forLink(l), true
+	case <-ctx.Done():
+		return LinkResult{URL: l.URL, Error: ErrorCanceled}.forLink(l), true
+	}
+}
@@ -0,0 +1,140 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLinkChecker_SharedCache(t *testing.T) {
+	var hits sync.Map
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := hits.LoadOrStore(r.URL.Path, new(atomic.Int32))
+		n.(*atomic.Int32).Add(1)
+		time.Sleep(10 * time.Millisecond)
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	c := NewLinkChecker().Shared()
+	pages := [][]Link{
+		{{URL: ts.URL + "/a", Text: "A"}, {URL: ts.URL + "/missing"}},
+		{{URL: ts.URL + "/a", Text: "A again", Kind: KindImage}, {URL: ts.URL + "/b"}},
+		{{URL: ts.URL + "/missing", Text: "Gone"}},
+	}
+	results := make([][]LinkResult, len(pages))
+	var wg sync.WaitGroup
+	for i, links := range pages {
+		wg.Go(func() { results[i] = c.Check(context.Background(), links) })
+	}
+	wg.Wait()
+
+	hits.Range(func(path, n any) bool {
+		if got := n.(*atomic.Int32).Load(); got != 1 {
+			t.Errorf("server saw %d requests for %s, want 1", got, path)
+		}
+		return true
+	})
+	if r := results[1][0]; !r.Accessible || r.Text != "A again" || r.Kind != KindImage {
+		t.Errorf("cached result = %+v, want accessible and describing its own link", r)
+	}
+	if r := results[2][0]; r.Accessible || r.StatusCode != http.StatusNotFound || r.Text != "Gone" {
+		t.Errorf("cached result = %+v, want 404 and text %q", r, "Gone")
+	}
+	if got, want := c.CacheStats(), (CacheStats{URLs: 3, Hits: 2}); got != want {
+		t.Errorf("CacheStats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestLinkChecker_SharedBudget(t *testing.T) {
+	var inflight, peak atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inflight.Add(1)
+		defer inflight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer ts.Close()
+
+	c := NewLinkChecker()
+	c.Workers = 10
+	c.PerHost = 3
+	shared := c.Shared()
+
+	var wg sync.WaitGroup
+	for p := range 4 {
+		links := make([]Link, 6)
+		for i := range links {
+			links[i] = Link{URL: fmt.Sprintf("%s/page/%d/%d", ts.URL, p, i)}
+		}
+		wg.Go(func() { shared.Check(context.Background(), links) })
+	}
+	wg.Wait()
+
+	if got := peak.Load(); got > 3 {
+		t.Errorf("peak concurrent requests to host = %d, want <= 3 across pages", got)
+	}
+}
+
+func TestLinkChecker_SharedCanceled(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	c := NewLinkChecker().Shared()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	links := []Link{{URL: ts.URL + "/a"}}
+	if r := c.Check(ctx, links)[0]; r.Error != ErrorCanceled {
+		t.Fatalf("result = %+v, want canceled", r)
+	}
+
+	if r := c.Check(context.Background(), links)[0]; !r.Accessible {
+		t.Errorf("result after cancellation = %+v, want a fresh, accessible check", r)
+	}
+}
+
+func TestLinkChecker_SharedOwnerCanceled(t *testing.T) {
+	started := make(chan struct{}, 1)
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			started <- struct{}{}
+			<-r.Context().Done()
+		}
+	}))
+	defer ts.Close()
+
+	c := NewLinkChecker().Shared()
+	c.Retry.MaxAttempts = 1
+	links := []Link{{URL: ts.URL + "/a"}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	owner := make(chan LinkResult)
+	go func() { owner <- c.Check(ctx, links)[0] }()
+	<-started
+
+	waiter := make(chan LinkResult)
+	go func() { waiter <- c.Check(context.Background(), links)[0] }()
+	// Let the second check find the first one in flight before canceling it.
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	if r := <-owner; r.Error != ErrorCanceled {
+		t.Errorf("owner result = %+v, want canceled", r)
+	}
+	if r := <-waiter; !r.Accessible {
+		t.Errorf("waiter result = %+v, want it to check the URL itself", r)
+	}
+}
@@ -0,0 +1,220 @@
+package analyzer
+
+import (
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// SocialMetadata holds the Open Graph and Twitter Card properties of a page,
+// grouped by namespace and keyed by full property name. Properties may
+// repeat, so every key maps to all its values in document order.
+type SocialMetadata struct {
+	OpenGraph map[string][]string `json:"openGraph"`
+	Article   map[string][]string `json:"article"`
+	Product   map[string][]string `json:"product"`
+	Twitter   map[string][]string `json:"twitter"`
+	// Missing lists required properties that are absent.
+	Missing []string `json:"missing"`
+	// Invalid lists properties whose values are malformed.
+	Invalid []SocialIssue `json:"invalid"`
+	// Images are the check results of the referenced share images.
+	Images []LinkResult `json:"images"`
+}
+
+// SocialIssue describes a malformed social metadata value.
+type SocialIssue struct {
+	Property string `json:"property"`
+	Value    string `json:"value"`
+	Reason   string `json:"reason"`
+}
+
+var requiredSocialProperties = []string{
+	"og:title",
+	"og:type",
+	"og:image",
+	"og:url",
+	"twitter:card",
+}
+
+var twitterCards = map[string]bool{
+	"summary":             true,
+	"summary_large_image": true,
+	"app":                 true,
+	"player":              true,
+}
+
+// socialImageProperties reference share images that should be reachable.
+var socialImageProperties = []string{
+	"og:image",
+	"og:image:url",
+	"og:image:secure_url",
+	"twitter:image",
+	"twitter:image:src",
+}
+
+// ExtractSocial parses the og:*, article:*, product:* and twitter:* meta
+// properties of doc and validates them. Images is left empty; see
+// SocialMetadata.ImageLinks.
+func ExtractSocial(doc *html.Node) SocialMetadata {
+	sm := SocialMetadata{
+		OpenGraph: map[string][]string{},
+		Article:   map[string][]string{},
+		Product:   map[string][]string{},
+		Twitter:   map[string][]string{},
+		Missing:   []string{},
+		Invalid:   []SocialIssue{},
+		Images:    []LinkResult{},
+	}
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "meta" {
+			sm.add(n)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	for _, p := range requiredSocialProperties {
+		if len(sm.values(p)) == 0 {
+			sm.Missing = append(sm.Missing, p)
+		}
+	}
+	sm.validate()
+	return sm
+}
+
+func (sm *SocialMetadata) add(n *html.Node) {
+	// Open Graph uses property=, Twitter Cards use name=; accept both.
+	prop, ok := attr(n, "property")
+	if !ok {
+		prop, _ = attr(n, "name")
+	}
+	prop = strings.ToLower(strings.TrimSpace(prop))
+	content, _ := attr(n, "content")
+	content = strings.TrimSpace(content)
+
+	if group := sm.group(prop); group != nil {
+		group[prop] = append(group[prop], content)
+	}
+}
+
+func (sm *SocialMetadata) group(prop string) map[string][]string {
+	ns, _, ok := strings.Cut(prop, ":")
+	if !ok {
+		return nil
+	}
+	switch ns {
+	case "og":
+		return sm.OpenGraph
+	case "article":
+		return sm.Article
+	case "product":
+		return sm.Product
+	case "twitter":
+		return sm.Twitter
+	}
+	return nil
+}
+
+func (sm *SocialMetadata) values(prop string) []string {
+	if g := sm.group(prop); g != nil {
+		return g[prop]
+	}
+	return nil
+}
+
+func (sm *SocialMetadata) validate() {
+	for _, g := range []map[string][]string{sm.OpenGraph, sm.Article, sm.Product, sm.Twitter} {
+		for _, prop := range slices.Sorted(maps.Keys(g)) {
+			for _, v := range g[prop] {
+				if reason := validateSocialValue(prop, v); reason != "" {
+					sm.Invalid = append(sm.Invalid, SocialIssue{Property: prop, Value: v, Reason: reason})
+				}
+			}
+		}
+	}
+}
+
+func validateSocialValue(prop, v string) string {
+	if v == "" {
+		return "empty value"
+	}
+
+	switch prop {
+	case "og:url", "og:image", "og:image:url", "og:video", "og:audio",
+		"twitter:image", "twitter:image:src":
+		if !isAbsoluteHTTPURL(v) {
+			return "must be an absolute http(s) URL"
+		}
+	case "og:image:secure_url", "og:video:secure_url", "og:audio:secure_url":
+		if u, err := url.Parse(v); err != nil || u.Scheme != "https" || u.Host == "" {
+			return "must be an absolute https URL"
+		}
+	case "og:image:width", "og:image:height", "og:video:width", "og:video:height":
+		if n, err := strconv.Atoi(v); err != nil || n <= 0 {
+			return "must be a positive integer"
+		}
+	case "article:published_time", "article:modified_time", "article:expiration_time":
+		if !isISO8601(v) {
+			return "must be an ISO 8601 date or date-time"
+		}
+	case "product:price:amount", "og:price:amount":
+		if _, err := strconv.ParseFloat(v, 64); err != nil {
+			return "must be a number"
+		}
+	case "twitter:card":
+		if !twitterCards[v] {
+			return "must be one of summary, summary_large_image, app or player"
+		}
+	}
+	return ""
+}
+
+func isAbsoluteHTTPURL(v string) bool {
+	u, err := url.Parse(v)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func isISO8601(v string) bool {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"} {
+		if _, err := time.Parse(layout, v); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// ImageLinks returns the share images referenced by the metadata, resolved
+// against base and classified by scope, ready for a LinkChecker.
+func (sm *SocialMetadata) ImageLinks(page, base *url.URL, scope Scope) []Link {
+	var links []Link
+	for _, prop := range socialImageProperties {
+		for _, v := range sm.values(prop) {
+			u, err := url.Parse(v)
+			if err != nil || v == "" {
+				continue
+			}
+			resolved := base.ResolveReference(u)
+			if resolved.Scheme != "http" && resolved.Scheme != "https" {
+				continue
+			}
+			links = append(links, Link{
+				URL:         resolved.String(),
+				Text:        prop,
+				Kind:        KindImage,
+				IsInternal:  scope.Contains(page, resolved),
+				Occurrences: 1,
+			})
+		}
+	}
+	return DedupeLinks(links, NormalizeOptions{})
+}
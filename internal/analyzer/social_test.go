@@ -0,0 +1,125 @@
+package analyzer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExtractSocial(t *testing.T) {
+	doc := parseHTML(t, `<html><head>
+		<meta property="og:title" content="Grey sofa">
+		<meta property="og:type" content="product">
+		<meta property="og:image" content="https://cdn.example.com/sofa-1.jpg">
+		<meta property="og:image" content="https://cdn.example.com/sofa-2.jpg">
+		<meta property="og:image:width" content="wide">
+		<meta property="og:image:secure_url" content="http://cdn.example.com/sofa-1.jpg">
+		<meta property="article:published_time" content="2024-05-01T10:00:00Z">
+		<meta property="product:price:amount" content="499.00">
+		<meta property="product:price:currency" content="EUR">
+		<meta name="twitter:card" content="large">
+		<meta name="twitter:image" content="/sofa-card.jpg">
+		<meta name="description" content="not social">
+	</head></html>`)
+
+	sm := ExtractSocial(doc)
+
+	if got := sm.OpenGraph["og:image"]; len(got) != 2 || got[1] != "https://cdn.example.com/sofa-2.jpg" {
+		t.Errorf("OpenGraph[og:image] = %q, want both images in order", got)
+	}
+	if got := sm.Article["article:published_time"]; len(got) != 1 {
+		t.Errorf("Article[article:published_time] = %q, want 1 value", got)
+	}
+	if got := sm.Product["product:price:currency"]; len(got) != 1 || got[0] != "EUR" {
+		t.Errorf("Product[product:price:currency] = %q, want [EUR]", got)
+	}
+	if got := sm.Twitter["twitter:card"]; len(got) != 1 || got[0] != "large" {
+		t.Errorf("Twitter[twitter:card] = %q, want [large]", got)
+	}
+
+	if len(sm.Missing) != 1 || sm.Missing[0] != "og:url" {
+		t.Errorf("Missing = %q, want [og:url]", sm.Missing)
+	}
+
+	wantInvalid := map[string]bool{
+		"og:image:width":      true,
+		"og:image:secure_url": true,
+		"twitter:card":        true,
+		"twitter:image":       true,
+	}
+	if len(sm.Invalid) != len(wantInvalid) {
+		t.Errorf("Invalid = %+v, want issues for %v", sm.Invalid, wantInvalid)
+	}
+	for _, issue := range sm.Invalid {
+		if !wantInvalid[issue.Property] {
+			t.Errorf("unexpected issue %+v", issue)
+		}
+	}
+}
+
+func TestExtractSocial_NoMetadata(t *testing.T) {
+	sm := ExtractSocial(parseHTML(t, `<html><head><title>Plain</title></head></html>`))
+	if len(sm.Missing) != len(requiredSocialProperties) {
+		t.Errorf("Missing = %q, want all required properties", sm.Missing)
+	}
+	if len(sm.Invalid) != 0 {
+		t.Errorf("Invalid = %+v, want none", sm.Invalid)
+	}
+}
+
+func TestValidateSocialValue(t *testing.T) {
+	tests := []struct {
+		prop, value string
+		valid       bool
+	}{
+		{"og:url", "https://example.com/a", true},
+		{"og:url", "/a", false},
+		{"og:image:secure_url", "https://example.com/a.jpg", true},
+		{"og:image:height", "630", true},
+		{"og:image:height", "-1", false},
+		{"article:modified_time", "2024-05-01", true},
+		{"article:modified_time", "yesterday", false},
+		{"product:price:amount", "12.5", true},
+		{"product:price:amount", "12,50", false},
+		{"twitter:card", "summary_large_image", true},
+		{"og:title", "", false},
+		{"og:site_name", "Anything goes", true},
+	}
+	for _, tt := range tests {
+		reason := validateSocialValue(tt.prop, tt.value)
+		if (reason == "") != tt.valid {
+			t.Errorf("validateSocialValue(%q, %q) = %q, want valid=%v", tt.prop, tt.value, reason, tt.valid)
+		}
+	}
+}
+
+func TestAnalyze_SocialImages(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/share.jpg" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	rawHTML := []byte(`<html><head>
+		<meta property="og:image" content="` + ts.URL + `/share.jpg">
+		<meta name="twitter:image" content="/missing.jpg">
+		<meta name="twitter:image:src" content="` + ts.URL + `/share.jpg#again">
+	</head></html>`)
+	resp, err := Analyze(context.Background(), rawHTML, ts.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	images := resp.Social.Images
+	if len(images) != 2 {
+		t.Fatalf("got %d social images, want 2: %+v", len(images), images)
+	}
+	if !images[0].Accessible || images[0].Occurrences != 2 {
+		t.Errorf("images[0] = %+v, want accessible with 2 occurrences", images[0])
+	}
+	if images[1].Accessible || images[1].URL != ts.URL+"/missing.jpg" {
+		t.Errorf("images[1] = %+v, want inaccessible %s/missing.jpg", images[1], ts.URL)
+	}
+}
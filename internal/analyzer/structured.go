@@ -0,0 +1,319 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Structured data sources.
+const (
+	SourceJSONLD    = "json-ld"
+	SourceMicrodata = "microdata"
+)
+
+// StructuredData holds the schema.org entities found in a page.
+type StructuredData struct {
+	Entities []Entity              `json:"entities"`
+	Errors   []StructuredDataError `json:"errors"`
+}
+
+// Entity is a top-level typed item from JSON-LD or microdata. Types are
+// reduced to their schema.org short names, e.g. "Product".
+type Entity struct {
+	Types      []string       `json:"types"`
+	Source     string         `json:"source"`
+	Properties map[string]any `json:"properties"`
+	// Missing lists the required properties, as dotted paths, that the
+	// entity lacks.
+	Missing []string `json:"missing"`
+}
+
+// StructuredDataError is a JSON-LD block that could not be parsed. Script is
+// the 1-based index of the block among the page's JSON-LD scripts; Line and
+// Column locate the error within it.
+type StructuredDataError struct {
+	Script  int    `json:"script"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+// DefaultRequiredProperties returns the properties each schema type must
+// have, as dotted paths into nested objects.
+func DefaultRequiredProperties() map[string][]string {
+	return map[string][]string{
+		"Product":        {"name", "offers.price"},
+		"BreadcrumbList": {"itemListElement"},
+		"Article":        {"headline"},
+		"Organization":   {"name"},
+	}
+}
+
+// ExtractStructuredData extracts JSON-LD and microdata entities from doc and
+// checks them against required.
+func ExtractStructuredData(doc *html.Node, required map[string][]string) StructuredData {
+	sd := StructuredData{Entities: []Entity{}, Errors: []StructuredDataError{}}
+
+	var scripts int
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if n.Data == "script" && isJSONLD(n) {
+				scripts++
+				sd.addJSONLD(scripts, rawText(n))
+				return
+			}
+			if _, ok := attr(n, "itemscope"); ok {
+				if _, isProp := attr(n, "itemprop"); !isProp {
+					sd.Entities = append(sd.Entities, Entity{
+						Types:      itemTypes(n),
+						Source:     SourceMicrodata,
+						Properties: microdataProperties(n),
+					})
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	for i := range sd.Entities {
+		sd.Entities[i].Missing = missingProperties(sd.Entities[i], required)
+	}
+	return sd
+}
+
+func isJSONLD(n *html.Node) bool {
+	t, _ := attr(n, "type")
+	t, _, _ = strings.Cut(t, ";")
+	return strings.EqualFold(strings.TrimSpace(t), "application/ld+json")
+}
+
+func rawText(n *html.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			b.WriteString(c.Data)
+		}
+	}
+	return b.String()
+}
+
+func (sd *StructuredData) addJSONLD(script int, src string) {
+	var v any
+	if err := json.Unmarshal([]byte(src), &v); err != nil {
+		line, col := 0, 0
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			// Offset counts the bytes read, including the offending one.
+			line, col = lineColumn(src, max(syntaxErr.Offset-1, 0))
+		}
+		sd.Errors = append(sd.Errors, StructuredDataError{
+			Script:  script,
+			Line:    line,
+			Column:  col,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	for _, obj := range jsonLDNodes(v) {
+		props := make(map[string]any, len(obj))
+		for k, val := range obj {
+			if k != "@context" && k != "@type" {
+				props[k] = val
+			}
+		}
+		sd.Entities = append(sd.Entities, Entity{
+			Types:      jsonLDTypes(obj["@type"]),
+			Source:     SourceJSONLD,
+			Properties: props,
+		})
+	}
+}
+
+// jsonLDNodes returns the top-level typed objects of a JSON-LD document,
+// unwrapping arrays and @graph containers.
+func jsonLDNodes(v any) []map[string]any {
+	switch v := v.(type) {
+	case []any:
+		var nodes []map[string]any
+		for _, item := range v {
+			nodes = append(nodes, jsonLDNodes(item)...)
+		}
+		return nodes
+	case map[string]any:
+		if graph, ok := v["@graph"]; ok {
+			return jsonLDNodes(graph)
+		}
+		if _, ok := v["@type"]; ok {
+			return []map[string]any{v}
+		}
+	}
+	return nil
+}
+
+func jsonLDTypes(v any) []string {
+	var types []string
+	switch v := v.(type) {
+	case string:
+		types = append(types, schemaName(v))
+	case []any:
+		for _, t := range v {
+			if s, ok := t.(string); ok {
+				types = append(types, schemaName(s))
+			}
+		}
+	}
+	return types
+}
+
+// schemaName strips the schema.org vocabulary prefix from a type.
+func schemaName(t string) string {
+	t = strings.TrimSpace(t)
+	for _, prefix := range []string{"https://schema.org/", "http://schema.org/", "schema:"} {
+		if rest, ok := strings.CutPrefix(t, prefix); ok {
+			return rest
+		}
+	}
+	return t
+}
+
+// lineColumn converts a byte offset into src to a 1-based line and column.
+func lineColumn(src string, offset int64) (int, int) {
+	if offset > int64(len(src)) {
+		offset = int64(len(src))
+	}
+	before := src[:offset]
+	line := strings.Count(before, "\n") + 1
+	col := int(offset) - strings.LastIndexByte(before, '\n')
+	return line, col
+}
+
+func itemTypes(n *html.Node) []string {
+	v, _ := attr(n, "itemtype")
+	var types []string
+	for _, t := range strings.Fields(v) {
+		types = append(types, schemaName(t))
+	}
+	return types
+}
+
+// microdataProperties collects the itemprop values belonging to the item
+// rooted at n. Nested items become nested objects. itemref is not followed.
+func microdataProperties(n *html.Node) map[string]any {
+	props := make(map[string]any)
+	var walk func(*html.Node)
+	walk = func(c *html.Node) {
+		for ; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+
+			names, isProp := attr(c, "itemprop")
+			_, isItem := attr(c, "itemscope")
+			if isProp {
+				var val any = microdataValue(c)
+				if isItem {
+					val = map[string]any{
+						"@type":      itemTypes(c),
+						"properties": microdataProperties(c),
+					}
+				}
+				for _, name := range strings.Fields(names) {
+					addProperty(props, name, val)
+				}
+			}
+			if !isItem {
+				walk(c.FirstChild)
+			}
+		}
+	}
+	walk(n.FirstChild)
+	return props
+}
+
+func addProperty(props map[string]any, name string, val any) {
+	switch existing := props[name].(type) {
+	case nil:
+		props[name] = val
+	case []any:
+		props[name] = append(existing, val)
+	default:
+		props[name] = []any{existing, val}
+	}
+}
+
+func microdataValue(n *html.Node) string {
+	var key string
+	switch n.Data {
+	case "meta":
+		key = "content"
+	case "audio", "embed", "iframe", "img", "source", "track", "video":
+		key = "src"
+	case "a", "area", "link":
+		key = "href"
+	case "object":
+		key = "data"
+	case "data", "meter":
+		key = "value"
+	case "time":
+		if v, ok := attr(n, "datetime"); ok {
+			return strings.TrimSpace(v)
+		}
+	}
+	if key != "" {
+		v, _ := attr(n, key)
+		return strings.TrimSpace(v)
+	}
+	return innerText(n)
+}
+
+func missingProperties(e Entity, required map[string][]string) []string {
+	missing := []string{}
+	for _, t := range e.Types {
+		for _, path := range required[t] {
+			if !hasPath(e.Properties, strings.Split(path, ".")) {
+				missing = append(missing, path)
+			}
+		}
+	}
+	return missing
+}
+
+// hasPath reports whether v has a non-empty value at path. Arrays match when
+// any element does, and microdata's nested "properties" wrapper is looked
+// through.
+func hasPath(v any, path []string) bool {
+	switch v := v.(type) {
+	case []any:
+		for _, item := range v {
+			if hasPath(item, path) {
+				return true
+			}
+		}
+		return false
+	case map[string]any:
+		if len(path) == 0 {
+			return len(v) > 0
+		}
+		if next, ok := v[path[0]]; ok {
+			return hasPath(next, path[1:])
+		}
+		if inner, ok := v["properties"]; ok {
+			return hasPath(inner, path)
+		}
+		return false
+	case string:
+		return len(path) == 0 && strings.TrimSpace(v) != ""
+	case nil:
+		return false
+	default:
+		return len(path) == 0
+	}
+}
@@ -0,0 +1,147 @@
+package analyzer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtractStructuredData_JSONLD(t *testing.T) {
+	doc := parseHTML(t, `<html><head>
+		<script type="application/ld+json">
+		{
+			"@context": "https://schema.org",
+			"@type": "Product",
+			"name": "Grey sofa",
+			"offers": {"@type": "Offer", "price": "499.00", "priceCurrency": "EUR"}
+		}
+		</script>
+		<script type="application/ld+json">
+		{
+			"@context": "https://schema.org",
+			"@graph": [
+				{"@type": "BreadcrumbList", "itemListElement": [{"@type": "ListItem", "position": 1, "name": "Home"}]},
+				{"@type": ["Product", "http://schema.org/IndividualProduct"], "name": "Lamp", "offers": [{"@type": "Offer"}]}
+			]
+		}
+		</script>
+		<script type="text/javascript">var notJSON = {;</script>
+	</head></html>`)
+
+	sd := ExtractStructuredData(doc, DefaultRequiredProperties())
+
+	if len(sd.Errors) != 0 {
+		t.Errorf("Errors = %+v, want none", sd.Errors)
+	}
+	if len(sd.Entities) != 3 {
+		t.Fatalf("got %d entities, want 3: %+v", len(sd.Entities), sd.Entities)
+	}
+
+	sofa := sd.Entities[0]
+	if sofa.Source != SourceJSONLD || strings.Join(sofa.Types, ",") != "Product" {
+		t.Errorf("entities[0] = %+v, want json-ld Product", sofa)
+	}
+	if _, ok := sofa.Properties["@context"]; ok {
+		t.Error("entities[0].Properties should not contain @context")
+	}
+	if len(sofa.Missing) != 0 {
+		t.Errorf("entities[0].Missing = %q, want none", sofa.Missing)
+	}
+
+	if got := strings.Join(sd.Entities[1].Types, ","); got != "BreadcrumbList" {
+		t.Errorf("entities[1].Types = %q, want BreadcrumbList", got)
+	}
+
+	lamp := sd.Entities[2]
+	if got := strings.Join(lamp.Types, ","); got != "Product,IndividualProduct" {
+		t.Errorf("entities[2].Types = %q, want Product,IndividualProduct", got)
+	}
+	if len(lamp.Missing) != 1 || lamp.Missing[0] != "offers.price" {
+		t.Errorf("entities[2].Missing = %q, want [offers.price]", lamp.Missing)
+	}
+}
+
+func TestExtractStructuredData_JSONLDErrors(t *testing.T) {
+	doc := parseHTML(t, `<html><head>
+		<script type="application/ld+json">{"@type": "Thing", "name": "ok"}</script>
+		<script type="application/ld+json">{
+  "@type": "Product",
+  "name": "Broken",
+}</script>
+	</head></html>`)
+
+	sd := ExtractStructuredData(doc, nil)
+
+	if len(sd.Entities) != 1 {
+		t.Errorf("got %d entities, want 1", len(sd.Entities))
+	}
+	if len(sd.Errors) != 1 {
+		t.Fatalf("got %d errors, want 1", len(sd.Errors))
+	}
+	got := sd.Errors[0]
+	if got.Script != 2 || got.Line != 4 || got.Column != 1 {
+		t.Errorf("error = %+v, want script 2, line 4, column 1", got)
+	}
+}
+
+func TestExtractStructuredData_Microdata(t *testing.T) {
+	doc := parseHTML(t, `<html><body>
+		<div itemscope itemtype="https://schema.org/Product">
+			<h1 itemprop="name">Grey sofa</h1>
+			<img itemprop="image" src="/sofa.jpg">
+			<a itemprop="url" href="/sofa">Link</a>
+			<span itemprop="color">Grey</span><span itemprop="color">Blue</span>
+			<div itemprop="offers" itemscope itemtype="https://schema.org/Offer">
+				<meta itemprop="price" content="499.00">
+				<span itemprop="name">not the product name</span>
+			</div>
+		</div>
+		<ol itemscope itemtype="https://schema.org/BreadcrumbList"></ol>
+	</body></html>`)
+
+	sd := ExtractStructuredData(doc, DefaultRequiredProperties())
+
+	if len(sd.Entities) != 2 {
+		t.Fatalf("got %d entities, want 2: %+v", len(sd.Entities), sd.Entities)
+	}
+
+	product := sd.Entities[0]
+	if product.Source != SourceMicrodata || strings.Join(product.Types, ",") != "Product" {
+		t.Errorf("entities[0] = %+v, want microdata Product", product)
+	}
+	if product.Properties["name"] != "Grey sofa" {
+		t.Errorf("name = %v, want %q", product.Properties["name"], "Grey sofa")
+	}
+	if product.Properties["image"] != "/sofa.jpg" || product.Properties["url"] != "/sofa" {
+		t.Errorf("image/url = %v/%v, want attribute values", product.Properties["image"], product.Properties["url"])
+	}
+	if colors, ok := product.Properties["color"].([]any); !ok || len(colors) != 2 {
+		t.Errorf("color = %v, want two values", product.Properties["color"])
+	}
+	if len(product.Missing) != 0 {
+		t.Errorf("entities[0].Missing = %q, want none", product.Missing)
+	}
+
+	breadcrumbs := sd.Entities[1]
+	if len(breadcrumbs.Missing) != 1 || breadcrumbs.Missing[0] != "itemListElement" {
+		t.Errorf("entities[1].Missing = %q, want [itemListElement]", breadcrumbs.Missing)
+	}
+}
+
+func TestLineColumn(t *testing.T) {
+	src := "ab\ncd\nef"
+	tests := []struct {
+		offset    int64
+		line, col int
+	}{
+		{0, 1, 1},
+		{1, 1, 2},
+		{3, 2, 1},
+		{7, 3, 2},
+		{100, 3, 3},
+	}
+	for _, tt := range tests {
+		if line, col := lineColumn(src, tt.offset); line != tt.line || col != tt.col {
+			t.Errorf("lineColumn(%d) = %d:%d, want %d:%d", tt.offset, line, col, tt.line, tt.col)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package analyzer
+
+// TransferMetrics describes how the analyzed page was downloaded.
+type TransferMetrics struct {
+	// ContentEncoding is the Content-Encoding the page was served with, if
+	// any.
+	ContentEncoding string `json:"contentEncoding,omitempty"`
+	// CompressedBytes is the size of the body on the wire and
+	// DecompressedBytes its size after decoding, both up to the limit.
+	CompressedBytes   int64 `json:"compressedBytes"`
+	DecompressedBytes int64 `json:"decompressedBytes"`
+	// TTFBMS is the time from sending the first request to receiving the
+	// first byte of the final response, redirects included, and DownloadMS
+	// the time until the body was read.
+	TTFBMS     int64 `json:"ttfbMs"`
+	DownloadMS int64 `json:"downloadMs"`
+	// Truncated is set when the decoded body exceeded LimitBytes and only
+	// its first LimitBytes were analyzed.
+	Truncated  bool  `json:"truncated"`
+	LimitBytes int64 `json:"limitBytes"`
+}
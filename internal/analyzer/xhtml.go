@@ -0,0 +1,63 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+)
+
+const mediaTypeXHTML = "application/xhtml+xml"
+
+// XMLReport is the result of checking an XHTML document for XML
+// well-formedness.
+type XMLReport struct {
+	WellFormed bool       `json:"wellFormed"`
+	Errors     []XMLError `json:"errors"`
+}
+
+// XMLError is a well-formedness error. Line and Column are 1-based and point
+// just past the token at which the error was detected.
+type XMLError struct {
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+// isXHTMLContentType reports whether contentType is application/xhtml+xml.
+func isXHTMLContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == mediaTypeXHTML
+}
+
+// CheckWellFormed parses doc, which must already be UTF-8, as XML. An XML
+// parser stops at the first fatal error, so at most one error is reported.
+// The named entities of HTML are accepted, as the XHTML DTDs declare them.
+func CheckWellFormed(doc []byte) XMLReport {
+	d := xml.NewDecoder(bytes.NewReader(doc))
+	d.Strict = true
+	d.Entity = xml.HTMLEntity
+	// The document has been transcoded already, whatever its declaration
+	// says.
+	d.CharsetReader = func(_ string, r io.Reader) (io.Reader, error) { return r, nil }
+
+	report := XMLReport{WellFormed: true, Errors: []XMLError{}}
+	for {
+		_, err := d.Token()
+		if err == io.EOF {
+			return report
+		}
+		if err != nil {
+			line, col := d.InputPos()
+			msg := err.Error()
+			var syntax *xml.SyntaxError
+			if errors.As(err, &syntax) {
+				msg = syntax.Msg
+			}
+			report.WellFormed = false
+			report.Errors = append(report.Errors, XMLError{Line: line, Column: col, Message: msg})
+			return report
+		}
+	}
+}
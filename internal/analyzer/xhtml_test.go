@@ -0,0 +1,50 @@
+package analyzer
+
+import "testing"
+
+func TestCheckWellFormed(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		wantLine int
+		wantCol  int
+	}{
+		{
+			name: "well-formed with entities and foreign declaration",
+			doc: `<?xml version="1.0" encoding="ISO-8859-1"?>
+<html xmlns="http://www.w3.org/1999/xhtml"><body><p>a&nbsp;b<br/></p></body></html>`,
+		},
+		{
+			name: "mismatched end tag",
+			doc: `<html xmlns="http://www.w3.org/1999/xhtml">
+<body><p>text</div></body></html>`,
+			wantLine: 2,
+			wantCol:  20,
+		},
+		{
+			name:     "unclosed element",
+			doc:      `<html xmlns="http://www.w3.org/1999/xhtml"><body><br></body></html>`,
+			wantLine: 1,
+			wantCol:  61,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CheckWellFormed([]byte(tt.doc))
+
+			if tt.wantLine == 0 {
+				if !got.WellFormed || len(got.Errors) != 0 {
+					t.Errorf("got %+v, want well-formed", got)
+				}
+				return
+			}
+			if got.WellFormed || len(got.Errors) != 1 {
+				t.Fatalf("got %+v, want one error", got)
+			}
+			if e := got.Errors[0]; e.Line != tt.wantLine || e.Column != tt.wantCol || e.Message == "" {
+				t.Errorf("error = %+v, want line %d column %d", e, tt.wantLine, tt.wantCol)
+			}
+		})
+	}
+}
@@ -0,0 +1,300 @@
+// Package crawler analyzes a site by walking it breadth-first from a seed
+// page, following the internal links found on every analyzed page, and
+// aggregates findings across the pages it visited.
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/moustafa/home24/internal/analyzer"
+)
+
+// Defaults for the zero fields of Options. DefaultMaxDepth is for callers
+// to apply, since a MaxDepth of zero is meaningful.
+const (
+	DefaultMaxDepth = 2
+	DefaultMaxPages = 50
+	DefaultWorkers  = 4
+)
+
+// Options bounds a crawl.
+type Options struct {
+	// MaxDepth is the number of link hops followed from the seed; 0 only
+	// analyzes the seed.
+	MaxDepth int
+	// MaxPages caps the number of pages analyzed, the seed included. Skipped
+	// pages do not count.
+	MaxPages int
+	// Include and Exclude are path patterns in path.Match syntax. A pattern
+	// matches a path when it matches the whole path or its leading
+	// segments, so "/blog" matches "/blog/2024/post". Discovered pages are
+	// followed if their path matches an Include pattern, or Include is empty,
+	// and matches no Exclude pattern. The seed is always analyzed.
+	Include []string
+	Exclude []string
+	// Normalize decides which URLs are the same page, as Analyze uses it to
+	// deduplicate links. Pages are fetched at the URL they were found with.
+	Normalize analyzer.NormalizeOptions
+	// Workers is the number of pages analyzed at once.
+	Workers int
+}
+
+// ValidatePatterns returns an error for the first malformed pattern.
+func ValidatePatterns(patterns []string) error {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid path pattern %q: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// AnalyzeFunc fetches and analyzes one page. It returns an error matching
+// ErrSkipped for pages that are not to be analyzed, such as non-HTML
+// documents.
+type AnalyzeFunc func(ctx context.Context, pageURL string) (*analyzer.AnalyzeResponse, error)
+
+// ErrSkipped marks a page AnalyzeFunc did not analyze. Skipped pages are
+// neither failed nor counted against MaxPages.
+var ErrSkipped = errors.New("page skipped")
+
+// Page is one analyzed page of a crawl.
+type Page struct {
+	URL   string `json:"url"`
+	Depth int    `json:"depth"`
+	// Referrer is the page the URL was first found on, empty for the seed.
+	Referrer string                    `json:"referrer,omitempty"`
+	Result   *analyzer.AnalyzeResponse `json:"result,omitempty"`
+	Err      error                     `json:"-"`
+}
+
+// Site aggregates the findings of every page of a crawl.
+type Site struct {
+	Pages  int `json:"pages"`
+	Failed int `json:"failed"`
+	// Skipped counts the pages found not to be analyzable, which Pages and
+	// Failed leave out.
+	Skipped int `json:"skipped"`
+	// Truncated is set when MaxPages left discovered pages unvisited.
+	Truncated bool `json:"truncated"`
+	// MissingH1 lists the pages without an h1 heading.
+	MissingH1       []string         `json:"missingH1"`
+	DuplicateTitles []DuplicateTitle `json:"duplicateTitles"`
+	BrokenLinks     []BrokenLink     `json:"brokenLinks"`
+}
+
+// DuplicateTitle is a non-empty title shared by several pages.
+type DuplicateTitle struct {
+	Title string   `json:"title"`
+	Pages []string `json:"pages"`
+}
+
+// BrokenLink is an inaccessible URL and the pages referencing it.
+type BrokenLink struct {
+	URL        string              `json:"url"`
+	StatusCode int                 `json:"statusCode,omitempty"`
+	Error      analyzer.ErrorClass `json:"error,omitempty"`
+	Pages      []string            `json:"pages"`
+}
+
+// Crawl analyzes seed and the internal pages reachable from it within
+// opts, level by level, and returns the site aggregates. onPage, if not
+// nil, is called with each page once analyzed; calls are never concurrent.
+// Only anchors that were found accessible are followed, and a page reached
+// through redirects is not visited again under its final URL.
+func Crawl(ctx context.Context, seed string, opts Options, analyze AnalyzeFunc, onPage func(Page)) Site {
+	if opts.MaxPages <= 0 {
+		opts.MaxPages = DefaultMaxPages
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = DefaultWorkers
+	}
+
+	var site Site
+	var pages []Page
+	seen := map[string]bool{analyzer.NormalizeURL(seed, opts.Normalize): true}
+	level := []Page{{URL: seed}}
+
+	var mu sync.Mutex
+	report := func(p Page) {
+		if onPage == nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		onPage(p)
+	}
+
+	for depth := 0; len(level) > 0 && ctx.Err() == nil; depth++ {
+		// Analyze the level in batches that fit MaxPages, so pages found to
+		// be skipped leave room for the rest of the level.
+		var analyzed []Page
+		for len(level) > 0 && ctx.Err() == nil {
+			n := min(len(level), opts.MaxPages-len(pages))
+			if n <= 0 {
+				site.Truncated = true
+				break
+			}
+			batch := level[:n]
+			level = level[n:]
+			analyzeLevel(ctx, batch, opts.Workers, analyze, report)
+			for _, p := range batch {
+				if errors.Is(p.Err, ErrSkipped) {
+					site.Skipped++
+					continue
+				}
+				pages = append(pages, p)
+				analyzed = append(analyzed, p)
+				if final, ok := finalURL(p.Result); ok {
+					seen[analyzer.NormalizeURL(final, opts.Normalize)] = true
+				}
+			}
+		}
+		if depth == opts.MaxDepth {
+			break
+		}
+
+		var next []Page
+		for _, p := range analyzed {
+			if p.Result == nil {
+				continue
+			}
+			for _, l := range p.Result.Links {
+				key := analyzer.NormalizeURL(l.URL, opts.Normalize)
+				if l.Kind != analyzer.KindAnchor || !l.IsInternal || !l.Accessible || seen[key] {
+					continue
+				}
+				seen[key] = true
+				if opts.follows(l.URL) {
+					next = append(next, Page{URL: l.URL, Depth: depth + 1, Referrer: p.URL})
+				}
+			}
+		}
+		level = next
+	}
+
+	site.aggregate(pages)
+	return site
+}
+
+func analyzeLevel(ctx context.Context, level []Page, workers int, analyze AnalyzeFunc, report func(Page)) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, workers)
+	for i := range level {
+		wg.Go(func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			p := &level[i]
+			p.Result, p.Err = analyze(ctx, p.URL)
+			report(*p)
+		})
+	}
+	wg.Wait()
+}
+
+// finalURL returns the URL the page of r was served from when it was
+// reached through redirects.
+func finalURL(r *analyzer.AnalyzeResponse) (string, bool) {
+	if r == nil || r.PageRedirects == nil || len(r.PageRedirects.Hops) == 0 {
+		return "", false
+	}
+	last := r.PageRedirects.Hops[len(r.PageRedirects.Hops)-1]
+	base, err := url.Parse(last.URL)
+	if err != nil {
+		return "", false
+	}
+	loc, err := url.Parse(last.Location)
+	if err != nil {
+		return "", false
+	}
+	return base.ResolveReference(loc).String(), true
+}
+
+// follows reports whether rawURL is an http or https URL whose path the
+// Include and Exclude patterns let through.
+func (o Options) follows(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	p := u.Path
+	if p == "" {
+		p = "/"
+	}
+	return (len(o.Include) == 0 || matchesAny(o.Include, p)) && !matchesAny(o.Exclude, p)
+}
+
+func matchesAny(patterns []string, p string) bool {
+	for _, pattern := range patterns {
+		for prefix := p; prefix != ""; {
+			if ok, _ := path.Match(pattern, prefix); ok {
+				return true
+			}
+			i := strings.LastIndex(prefix, "/")
+			if i <= 0 {
+				break
+			}
+			prefix = prefix[:i]
+		}
+	}
+	return false
+}
+
+func (s *Site) aggregate(pages []Page) {
+	s.Pages = len(pages)
+	s.MissingH1 = []string{}
+	s.DuplicateTitles = []DuplicateTitle{}
+	s.BrokenLinks = []BrokenLink{}
+
+	titles := make(map[string]int)
+	var titled []DuplicateTitle
+	broken := make(map[string]int)
+	for _, p := range pages {
+		r := p.Result
+		if r == nil {
+			s.Failed++
+			continue
+		}
+		if r.Headings["h1"] == 0 {
+			s.MissingH1 = append(s.MissingH1, p.URL)
+		}
+
+		if title := strings.TrimSpace(r.Title); title != "" {
+			if i, ok := titles[title]; ok {
+				titled[i].Pages = append(titled[i].Pages, p.URL)
+			} else {
+				titles[title] = len(titled)
+				titled = append(titled, DuplicateTitle{Title: title, Pages: []string{p.URL}})
+			}
+		}
+
+		for _, l := range r.Links {
+			if !l.Inaccessible() {
+				continue
+			}
+			i, ok := broken[l.URL]
+			if !ok {
+				i = len(s.BrokenLinks)
+				broken[l.URL] = i
+				s.BrokenLinks = append(s.BrokenLinks, BrokenLink{URL: l.URL, StatusCode: l.StatusCode, Error: l.Error})
+			}
+			// A URL used as both anchor and image appears twice on a page.
+			if refs := s.BrokenLinks[i].Pages; len(refs) == 0 || refs[len(refs)-1] != p.URL {
+				s.BrokenLinks[i].Pages = append(refs, p.URL)
+			}
+		}
+	}
+
+	for _, t := range titled {
+		if len(t.Pages) > 1 {
+			s.DuplicateTitles = append(s.DuplicateTitles, t)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/moustafa/home24/internal/analyzer"
+)
+
+const site = "https://example.com"
+
+// page is a fake page: its title, whether it has an h1, and its links. A
+// page with redirect was served from that path after a redirect, and one
+// with skip is not HTML.
+type page struct {
+	title    string
+	h1       bool
+	links    []analyzer.LinkResult
+	redirect string
+	skip     bool
+}
+
+func anchor(path string) analyzer.LinkResult {
+	return analyzer.LinkResult{URL: site + path, Kind: analyzer.KindAnchor, IsInternal: true, Accessible: true}
+}
+
+func brokenLink(path string, status int) analyzer.LinkResult {
+	l := anchor(path)
+	l.Accessible, l.StatusCode, l.Error = false, status, analyzer.ErrorStatus
+	return l
+}
+
+// fakeSite returns an AnalyzeFunc serving pages and recording the URLs it
+// was asked for, as a server sees them: without fragment and with an empty
+// path as /.
+func fakeSite(pages map[string]page) (AnalyzeFunc, func() []string) {
+	var mu sync.Mutex
+	var visited []string
+	analyze := func(_ context.Context, pageURL string) (*analyzer.AnalyzeResponse, error) {
+		pageURL, _, _ = strings.Cut(pageURL, "#")
+		if pageURL == site {
+			pageURL += "/"
+		}
+		mu.Lock()
+		visited = append(visited, pageURL)
+		mu.Unlock()
+		p, ok := pages[pageURL[len(site):]]
+		if !ok {
+			return nil, errors.New("not found")
+		}
+		if p.skip {
+			return nil, fmt.Errorf("%w: not HTML", ErrSkipped)
+		}
+		resp := &analyzer.AnalyzeResponse{Title: p.title, Headings: map[string]int{}, Links: p.links}
+		if p.redirect != "" {
+			resp.PageRedirects = &analyzer.RedirectChain{Hops: []analyzer.RedirectHop{
+				{URL: pageURL, StatusCode: http.StatusMovedPermanently, Location: p.redirect},
+			}}
+		}
+		if p.h1 {
+			resp.Headings["h1"] = 1
+		}
+		return resp, nil
+	}
+	return analyze, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Sorted(slices.Values(visited))
+	}
+}
+
+var testPages = map[string]page{
+	"/": {title: "Home", h1: true, links: []analyzer.LinkResult{
+		anchor("/about"), anchor("/blog"), brokenLink("/gone", 404),
+		{URL: "https://other.example/", Kind: analyzer.KindAnchor, Accessible: true},
+		{URL: site + "/logo.png", Kind: analyzer.KindImage, IsInternal: true, Accessible: true},
+	}},
+	"/about":     {title: "About", links: []analyzer.LinkResult{anchor("/"), anchor("/team")}},
+	"/blog":      {title: "Home", h1: true, links: []analyzer.LinkResult{anchor("/blog/post"), brokenLink("/gone", 404)}},
+	"/team":      {title: "Team", h1: true},
+	"/blog/post": {title: "Post", h1: true, links: []analyzer.LinkResult{anchor("/blog/post/comments")}},
+}
+
+func TestCrawl(t *testing.T) {
+	analyze, visited := fakeSite(testPages)
+	var reported []string
+	s := Crawl(context.Background(), site, Options{MaxDepth: 2}, analyze, func(p Page) {
+		reported = append(reported, p.URL)
+	})
+
+	want := []string{site + "/", site + "/about", site + "/blog", site + "/blog/post", site + "/team"}
+	if got := visited(); !slices.Equal(got, want) {
+		t.Errorf("visited %v, want %v", got, want)
+	}
+	if len(reported) != len(want) {
+		t.Errorf("reported %d pages, want %d", len(reported), len(want))
+	}
+
+	// Pages are reported at the URL they were found with, the seed as given.
+	wantSite := Site{
+		Pages:           5,
+		MissingH1:       []string{site + "/about"},
+		DuplicateTitles: []DuplicateTitle{{Title: "Home", Pages: []string{site, site + "/blog"}}},
+		BrokenLinks: []BrokenLink{{
+			URL: site + "/gone", StatusCode: 404, Error: analyzer.ErrorStatus,
+			Pages: []string{site, site + "/blog"},
+		}},
+	}
+	if !reflect.DeepEqual(s, wantSite) {
+		t.Errorf("site = %+v, want %+v", s, wantSite)
+	}
+}
+
+func TestCrawl_Limits(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          Options
+		want          []string
+		wantTruncated bool
+	}{
+		{"seed only", Options{MaxDepth: 0}, []string{"/"}, false},
+		{"depth", Options{MaxDepth: 1}, []string{"/", "/about", "/blog"}, false},
+		{"pages", Options{MaxDepth: 5, MaxPages: 2}, []string{"/", "/about"}, true},
+		{"include", Options{MaxDepth: 5, Include: []string{"/blog"}}, []string{"/", "/blog", "/blog/post", "/blog/post/comments"}, false},
+		{"exclude", Options{MaxDepth: 5, Exclude: []string{"/blog/*", "/team"}}, []string{"/", "/about", "/blog"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyze, visited := fakeSite(testPages)
+			s := Crawl(context.Background(), site+"/#top", tt.opts, analyze, nil)
+
+			var want []string
+			for _, p := range tt.want {
+				want = append(want, site+p)
+			}
+			slices.Sort(want)
+			if got := visited(); !slices.Equal(got, want) {
+				t.Errorf("visited %v, want %v", got, want)
+			}
+			if s.Truncated != tt.wantTruncated {
+				t.Errorf("Truncated = %v, want %v", s.Truncated, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestCrawl_Failed(t *testing.T) {
+	analyze, _ := fakeSite(map[string]page{"/": {links: []analyzer.LinkResult{anchor("/missing")}}})
+	var errs int
+	s := Crawl(context.Background(), site, Options{MaxDepth: 1}, analyze, func(p Page) {
+		if p.Err != nil {
+			errs++
+		}
+	})
+
+	if s.Pages != 2 || s.Failed != 1 || errs != 1 {
+		t.Errorf("pages = %d, failed = %d, errors reported = %d; want 2, 1, 1", s.Pages, s.Failed, errs)
+	}
+}
+
+func TestCrawl_Skipped(t *testing.T) {
+	pages := map[string]page{
+		"/":        {h1: true, links: []analyzer.LinkResult{anchor("/doc.pdf"), anchor("/a"), anchor("/b")}},
+		"/doc.pdf": {skip: true},
+		"/a":       {h1: true},
+		"/b":       {h1: true},
+	}
+	analyze, visited := fakeSite(pages)
+	var skipped int
+	s := Crawl(context.Background(), site, Options{MaxDepth: 1, MaxPages: 3}, analyze, func(p Page) {
+		if errors.Is(p.Err, ErrSkipped) {
+			skipped++
+		}
+	})
+
+	want := []string{site + "/", site + "/a", site + "/b", site + "/doc.pdf"}
+	if got := visited(); !slices.Equal(got, want) {
+		t.Errorf("visited %v, want %v", got, want)
+	}
+	if s.Pages != 3 || s.Failed != 0 || s.Skipped != 1 || skipped != 1 || s.Truncated {
+		t.Errorf("site = %+v, skipped reported = %d; want 3 pages, 1 skipped, none failed", s, skipped)
+	}
+}
+
+func TestCrawl_Redirected(t *testing.T) {
+	pages := map[string]page{
+		"/":    {h1: true, links: []analyzer.LinkResult{anchor("/old"), anchor("/a")}},
+		"/old": {h1: true, redirect: "/new"},
+		"/a":   {h1: true, links: []analyzer.LinkResult{anchor("/new")}},
+		"/new": {h1: true},
+	}
+	analyze, visited := fakeSite(pages)
+	Crawl(context.Background(), site, Options{MaxDepth: 2}, analyze, nil)
+
+	want := []string{site + "/", site + "/a", site + "/old"}
+	if got := visited(); !slices.Equal(got, want) {
+		t.Errorf("visited %v, want %v; /new was already analyzed through /old", got, want)
+	}
+}
+
+func TestMatchesAny(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"/blog", "/blog", true},
+		{"/blog", "/blog/2024/post", true},
+		{"/blog", "/blogroll", false},
+		{"/products/*/reviews", "/products/chair/reviews/2", true},
+		{"/products/*/reviews", "/products/chair", false},
+		{"/*.pdf", "/files.pdf", true},
+		{"/", "/anything", false},
+	}
+	for _, tt := range tests {
+		if got := matchesAny([]string{tt.pattern}, tt.path); got != tt.want {
+			t.Errorf("matchesAny(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestValidatePatterns(t *testing.T) {
+	if err := ValidatePatterns([]string{"/blog/*", "/a[bc]"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ValidatePatterns([]string{"/blog/[a"}); err == nil {
+		t.Error("want an error for a malformed pattern")
+	}
+}
@@ -3,28 +3,144 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
+	"sync"
 	"time"
 
 	"github.com/moustafa/home24/internal/analyzer"
+	"github.com/moustafa/home24/internal/jobs"
+	"github.com/moustafa/home24/internal/netguard"
 )
 
-var httpClient = &http.Client{Timeout: 10 * time.Second}
+// Config holds the settings shared by all requests served by a Handler.
+type Config struct {
+	// LinkChecker checks the links found on analyzed pages. A LinkChecker
+	// with default settings is used when nil.
+	LinkChecker *analyzer.LinkChecker
+	// LongRedirectChain is the number of hops above which the redirect chain
+	// of the analyzed page is flagged as too long.
+	// analyzer.DefaultLongRedirectChain is used when zero.
+	LongRedirectChain int
+	// Guard decides which addresses pages and links may be fetched from. A
+	// Guard refusing every non-public address is used when nil.
+	Guard *netguard.Guard
+	// Jobs runs the analyses submitted to the job API. When nil, a Manager
+	// with default settings is started on the first job request and runs for
+	// the life of the process. The Handler never closes it.
+	Jobs *jobs.Manager
+	// BatchConcurrency is the number of pages analyzed at once across all
+	// batch and crawl requests. DefaultBatchConcurrency is used when zero.
+	BatchConcurrency int
+}
+
+// DefaultBatchConcurrency is the default Config.BatchConcurrency.
+const DefaultBatchConcurrency = 4
+
+// Handler serves the analysis API.
+type Handler struct {
+	cfg    Config
+	client *http.Client
+	// batchSlots holds a token per page being analyzed for a batch or crawl.
+	batchSlots chan struct{}
+	// jobManager returns cfg.Jobs or the default Manager.
+	jobManager func() *jobs.Manager
+}
+
+// New returns a Handler for cfg. Both the page fetch and the link checks
+// dial through cfg.Guard: New installs the guard's transport on a copy of
+// the LinkChecker's client.
+func New(cfg Config) *Handler {
+	if cfg.LinkChecker == nil {
+		cfg.LinkChecker = analyzer.NewLinkChecker()
+	}
+	if cfg.Guard == nil {
+		cfg.Guard = &netguard.Guard{}
+	}
+	if cfg.BatchConcurrency <= 0 {
+		cfg.BatchConcurrency = DefaultBatchConcurrency
+	}
+
+	checker := *cfg.LinkChecker
+	var linkClient http.Client
+	if checker.Client != nil {
+		linkClient = *checker.Client
+	}
+	linkClient.Transport = cfg.Guard.Transport()
+	checker.Client = &linkClient
+	cfg.LinkChecker = &checker
+
+	return &Handler{
+		cfg: cfg,
+		client: &http.Client{
+			Timeout:       10 * time.Second,
+			CheckRedirect: analyzer.CheckRedirect,
+			Transport:     cfg.Guard.Transport(),
+		},
+		batchSlots: make(chan struct{}, cfg.BatchConcurrency),
+		jobManager: sync.OnceValue(func() *jobs.Manager {
+			if cfg.Jobs != nil {
+				return cfg.Jobs
+			}
+			return jobs.New(jobs.Config{})
+		}),
+	}
+}
 
 type analyzeRequest struct {
-	URL string `json:"url"`
+	URL       string                    `json:"url"`
+	Scope     analyzer.Scope            `json:"scope"`
+	Normalize analyzer.NormalizeOptions `json:"normalize"`
+	// MetadataLimits overrides the default title and description limits;
+	// limits it leaves out keep their defaults.
+	MetadataLimits *metadataLimits `json:"metadataLimits"`
+	// RequiredProperties overrides the required structured data properties
+	// per schema type.
+	RequiredProperties map[string][]string `json:"requiredProperties"`
+}
+
+// metadataLimits decodes onto analyzer.DefaultMetadataLimits, so a partial
+// object only overrides the limits it names.
+type metadataLimits analyzer.MetadataLimits
+
+func (l *metadataLimits) UnmarshalJSON(b []byte) error {
+	limits := analyzer.DefaultMetadataLimits()
+	if err := json.Unmarshal(b, &limits); err != nil {
+		return err
+	}
+	*l = metadataLimits(limits)
+	return nil
 }
 
 type errorResponse struct {
-	StatusCode int    `json:"statusCode"`
-	Message    string `json:"message"`
+	StatusCode int `json:"statusCode"`
+	// Code identifies the kind of failure where the status code alone is
+	// ambiguous.
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+	// Redirects holds the hops followed before a fetch failed while
+	// redirecting.
+	Redirects *analyzer.RedirectChain `json:"redirects,omitempty"`
 }
 
-func Analyze(w http.ResponseWriter, r *http.Request) {
+func (e *errorResponse) Error() string { return e.Message }
+
+// Error codes.
+const (
+	codeUnsupportedMediaType = "unsupported_media_type"
+	codeTargetBlocked        = "target_blocked"
+	codeQueueFull            = "queue_full"
+	codeJobFinished          = "job_finished"
+	codeRedirectLoop         = "redirect_loop"
+	codeTooManyRedirects     = "too_many_redirects"
+)
+
+func (h *Handler) Analyze(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
@@ -36,50 +152,178 @@ func Analyze(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	result, errResp := h.analyze(r.Context(), req, h.cfg.LinkChecker, nil)
+	if errResp != nil {
+		writeJSON(w, errResp.StatusCode, errResp)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// validate checks the URL of req and returns its normalized scope.
+func (req analyzeRequest) validate() (analyzer.Scope, *errorResponse) {
 	parsed, err := url.ParseRequestURI(req.URL)
 	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
-		writeError(w, http.StatusBadRequest, "url must be an absolute URL with http or https scheme")
-		return
+		return analyzer.Scope{}, newError(http.StatusBadRequest, "", "url must be an absolute URL with http or https scheme")
+	}
+	scope, err := req.Scope.Normalize()
+	if err != nil {
+		return analyzer.Scope{}, newError(http.StatusBadRequest, "", err.Error())
+	}
+	return scope, nil
+}
+
+// analyze fetches and analyzes the page of req, checking its links with
+// checker. If emit is not nil, it is called with the name and payload of an
+// event as each phase completes.
+func (h *Handler) analyze(ctx context.Context, req analyzeRequest, checker *analyzer.LinkChecker, emit func(event string, data any)) (*analyzer.AnalyzeResponse, *errorResponse) {
+	scope, errResp := req.validate()
+	if errResp != nil {
+		return nil, errResp
 	}
 
-	body, statusCode, finalURL, err := fetchURL(r.Context(), req.URL)
+	page, err := h.fetchURL(ctx, req.URL)
 	if err != nil {
-		writeError(w, http.StatusBadGateway, fmt.Sprintf("failed to fetch URL: %v", err))
-		return
+		var blocked *netguard.BlockedError
+		if errors.As(err, &blocked) {
+			return nil, newError(http.StatusForbidden, codeTargetBlocked, fmt.Sprintf("refusing to fetch URL: %v", blocked))
+		}
+		errResp := newError(http.StatusBadGateway, "", fmt.Sprintf("failed to fetch URL: %v", err))
+		var redirect *redirectError
+		if errors.As(err, &redirect) {
+			errResp.Redirects = &redirect.chain
+			switch {
+			case errors.Is(err, analyzer.ErrRedirectLoop):
+				errResp.Code = codeRedirectLoop
+			case errors.Is(err, analyzer.ErrTooManyRedirects):
+				errResp.Code = codeTooManyRedirects
+			}
+		}
+		return nil, errResp
 	}
 
-	if statusCode >= 400 {
-		writeError(w, statusCode, fmt.Sprintf("upstream returned status %d", statusCode))
-		return
+	if page.statusCode >= 400 {
+		return nil, newError(page.statusCode, "", fmt.Sprintf("upstream returned status %d", page.statusCode))
 	}
 
-	result, err := analyzer.Analyze(r.Context(), body, finalURL)
+	contentType, err := pageContentType(page.contentType, page.body)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("analysis failed: %v", err))
-		return
+		return nil, newError(http.StatusUnsupportedMediaType, codeUnsupportedMediaType, err.Error())
 	}
 
-	writeJSON(w, http.StatusOK, result)
+	opts := []analyzer.Option{
+		analyzer.WithLinkChecker(checker),
+		analyzer.WithScope(scope),
+		analyzer.WithNormalization(req.Normalize),
+		analyzer.WithContentType(contentType),
+	}
+	if req.MetadataLimits != nil {
+		opts = append(opts, analyzer.WithMetadataLimits(analyzer.MetadataLimits(*req.MetadataLimits)))
+	}
+	if req.RequiredProperties != nil {
+		opts = append(opts, analyzer.WithRequiredProperties(req.RequiredProperties))
+	}
+	if emit != nil {
+		emit(eventFetched, fetchedEvent{
+			URL:         page.finalURL,
+			StatusCode:  page.statusCode,
+			ContentType: contentType,
+			Transfer:    page.transfer,
+		})
+		opts = append(opts, analyzer.WithProgress(func(p analyzer.Progress) {
+			emit(string(p.Phase), p)
+		}))
+	}
+
+	result, err := analyzer.Analyze(ctx, page.body, page.finalURL, opts...)
+	if err != nil {
+		return nil, newError(http.StatusInternalServerError, "", fmt.Sprintf("analysis failed: %v", err))
+	}
+	if len(page.redirects.Hops) > 0 {
+		result.PageRedirects = &page.redirects
+	}
+	result.Transfer = &page.transfer
+	return result, nil
+}
+
+// maxBodyBytes is the most of a decoded page body that is analyzed.
+const maxBodyBytes = 10 << 20
+
+// fetchResult is a fetched page.
+type fetchResult struct {
+	body        []byte
+	statusCode  int
+	contentType string
+	finalURL    string
+	redirects   analyzer.RedirectChain
+	transfer    analyzer.TransferMetrics
 }
 
-func fetchURL(ctx context.Context, rawURL string) (body []byte, statusCode int, finalURL string, err error) {
+// redirectError is a fetch that failed after following redirects.
+type redirectError struct {
+	chain analyzer.RedirectChain
+	err   error
+}
+
+func (e *redirectError) Error() string { return e.err.Error() }
+func (e *redirectError) Unwrap() error { return e.err }
+
+func (h *Handler) fetchURL(ctx context.Context, rawURL string) (*fetchResult, error) {
+	start := time.Now()
+	var firstByte time.Time
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotFirstResponseByte: func() { firstByte = time.Now() },
+	})
+	ctx, trace := analyzer.TraceRedirects(ctx)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
 	if err != nil {
-		return nil, 0, "", fmt.Errorf("creating request: %w", err)
+		return nil, fmt.Errorf("creating request: %w", err)
 	}
+	req.Header.Set("Accept-Encoding", acceptEncoding)
 
-	resp, err := httpClient.Do(req)
+	resp, err := h.client.Do(req)
 	if err != nil {
-		return nil, 0, "", fmt.Errorf("fetching URL: %w", err)
+		err = fmt.Errorf("fetching URL: %w", err)
+		if chain := trace.Chain(h.cfg.LongRedirectChain); len(chain.Hops) > 0 {
+			return nil, &redirectError{chain: chain, err: err}
+		}
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	b, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	wire := &countingReader{r: resp.Body}
+	contentEncoding := resp.Header.Get("Content-Encoding")
+	decoded, err := decodeBody(wire, contentEncoding)
+	if err != nil {
+		return nil, err
+	}
+	// Read one byte past the limit to tell a body of exactly maxBodyBytes
+	// from a longer one.
+	b, err := io.ReadAll(io.LimitReader(decoded, maxBodyBytes+1))
 	if err != nil {
-		return nil, 0, "", fmt.Errorf("reading response: %w", err)
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+	truncated := len(b) > maxBodyBytes
+	if truncated {
+		b = b[:maxBodyBytes]
 	}
 
-	return b, resp.StatusCode, resp.Request.URL.String(), nil
+	return &fetchResult{
+		body:        b,
+		statusCode:  resp.StatusCode,
+		contentType: resp.Header.Get("Content-Type"),
+		finalURL:    resp.Request.URL.String(),
+		redirects:   trace.Chain(h.cfg.LongRedirectChain),
+		transfer: analyzer.TransferMetrics{
+			ContentEncoding:   contentEncoding,
+			CompressedBytes:   wire.n,
+			DecompressedBytes: int64(len(b)),
+			TTFBMS:            firstByte.Sub(start).Milliseconds(),
+			DownloadMS:        time.Since(start).Milliseconds(),
+			Truncated:         truncated,
+			LimitBytes:        maxBodyBytes,
+		},
+	}, nil
 }
 
 func writeJSON(w http.ResponseWriter, status int, v any) {
@@ -90,9 +334,10 @@ func writeJSON(w http.ResponseWriter, status int, v any) {
 	}
 }
 
+func newError(status int, code, message string) *errorResponse {
+	return &errorResponse{StatusCode: status, Code: code, Message: message}
+}
+
 func writeError(w http.ResponseWriter, status int, message string) {
-	writeJSON(w, status, errorResponse{
-		StatusCode: status,
-		Message:    message,
-	})
+	writeJSON(w, status, newError(status, "", message))
 }
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -9,8 +10,19 @@ import (
 	"testing"
 
 	"github.com/moustafa/home24/internal/analyzer"
+	"github.com/moustafa/home24/internal/netguard"
 )
 
+// testConfig allowlists loopback addresses, where the test servers listen.
+func testConfig(t *testing.T) Config {
+	t.Helper()
+	guard, err := netguard.New([]string{"127.0.0.0/8", "::1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Config{Guard: guard}
+}
+
 func TestAnalyze_Success(t *testing.T) {
 	htmlBody := `<!DOCTYPE html><html><head><title>Test Page</title></head><body><h1>Hello</h1><h2>World</h2></body></html>`
 	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -22,7 +34,7 @@ func TestAnalyze_Success(t *testing.T) {
 	req := httptest.NewRequest(http.MethodPost, "/api/analyze", bytes.NewReader(body))
 	rec := httptest.NewRecorder()
 
-	Analyze(rec, req)
+	New(testConfig(t)).Analyze(rec, req)
 
 	if rec.Code != http.StatusOK {
 		t.Fatalf("status = %d, want 200", rec.Code)
@@ -46,11 +58,39 @@ func TestAnalyze_Success(t *testing.T) {
 	}
 }
 
+func TestAnalyze_PartialMetadataLimits(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><title>Test Page</title><meta name="description" content="Short."></head></html>`))
+	}))
+	defer upstream.Close()
+
+	body := `{"url": "` + upstream.URL + `", "metadataLimits": {"titleMax": 70}}`
+	req := httptest.NewRequest(http.MethodPost, "/api/analyze", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	New(testConfig(t)).Analyze(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	var resp analyzer.AnalyzeResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	defaults := analyzer.DefaultMetadataLimits()
+	if got := resp.Metadata.TitleLength; got.Min != defaults.TitleMin || got.Max != 70 {
+		t.Errorf("TitleLength limits = %d..%d, want %d..70", got.Min, got.Max, defaults.TitleMin)
+	}
+	if got := resp.Metadata.DescriptionLength; got.Min != defaults.DescriptionMin || got.Max != defaults.DescriptionMax {
+		t.Errorf("DescriptionLength limits = %d..%d, want the defaults", got.Min, got.Max)
+	}
+}
+
 func TestAnalyze_InvalidJSON(t *testing.T) {
 	req := httptest.NewRequest(http.MethodPost, "/api/analyze", strings.NewReader("not json"))
 	rec := httptest.NewRecorder()
 
-	Analyze(rec, req)
+	New(testConfig(t)).Analyze(rec, req)
 
 	if rec.Code != http.StatusBadRequest {
 		t.Errorf("status = %d, want 400", rec.Code)
@@ -62,7 +102,7 @@ func TestAnalyze_InvalidURL(t *testing.T) {
 	req := httptest.NewRequest(http.MethodPost, "/api/analyze", bytes.NewReader(body))
 	rec := httptest.NewRecorder()
 
-	Analyze(rec, req)
+	New(testConfig(t)).Analyze(rec, req)
 
 	if rec.Code != http.StatusBadRequest {
 		t.Errorf("status = %d, want 400", rec.Code)
@@ -73,7 +113,7 @@ func TestAnalyze_MethodNotAllowed(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/analyze", nil)
 	rec := httptest.NewRecorder()
 
-	Analyze(rec, req)
+	New(testConfig(t)).Analyze(rec, req)
 
 	if rec.Code != http.StatusMethodNotAllowed {
 		t.Errorf("status = %d, want 405", rec.Code)
@@ -85,9 +125,357 @@ func TestAnalyze_FetchError(t *testing.T) {
 	req := httptest.NewRequest(http.MethodPost, "/api/analyze", bytes.NewReader(body))
 	rec := httptest.NewRecorder()
 
-	Analyze(rec, req)
+	New(testConfig(t)).Analyze(rec, req)
 
 	if rec.Code != http.StatusBadGateway {
 		t.Errorf("status = %d, want 502", rec.Code)
 	}
 }
+
+func TestAnalyze_Scope(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><body><a href="/about">About</a></body></html>`))
+	}))
+	defer upstream.Close()
+
+	body, _ := json.Marshal(analyzeRequest{
+		URL:   upstream.URL,
+		Scope: analyzer.Scope{Mode: analyzer.ScopeDomain, Aliases: []string{"CDN.example.com"}},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/analyze", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	New(testConfig(t)).Analyze(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	var resp analyzer.AnalyzeResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Scope.Mode != analyzer.ScopeDomain {
+		t.Errorf("Scope.Mode = %q, want %q", resp.Scope.Mode, analyzer.ScopeDomain)
+	}
+	if len(resp.Scope.Aliases) != 1 || resp.Scope.Aliases[0] != "cdn.example.com" {
+		t.Errorf("Scope.Aliases = %q, want [cdn.example.com]", resp.Scope.Aliases)
+	}
+	if resp.InternalLinks != 1 {
+		t.Errorf("InternalLinks = %d, want 1", resp.InternalLinks)
+	}
+}
+
+func TestAnalyze_InvalidScope(t *testing.T) {
+	body := `{"url": "http://example.com", "scope": {"mode": "galaxy"}}`
+	req := httptest.NewRequest(http.MethodPost, "/api/analyze", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	New(testConfig(t)).Analyze(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+}
+
+func TestAnalyze_PageRedirects(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+		case "/new":
+			w.Write([]byte(`<html><head><title>New</title></head></html>`))
+		}
+	}))
+	defer upstream.Close()
+
+	body, _ := json.Marshal(analyzeRequest{URL: upstream.URL + "/old"})
+	req := httptest.NewRequest(http.MethodPost, "/api/analyze", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	New(testConfig(t)).Analyze(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	var resp analyzer.AnalyzeResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.PageRedirects == nil || len(resp.PageRedirects.Hops) != 1 {
+		t.Fatalf("PageRedirects = %+v, want 1 hop", resp.PageRedirects)
+	}
+	hop := resp.PageRedirects.Hops[0]
+	if hop.URL != upstream.URL+"/old" || hop.StatusCode != http.StatusMovedPermanently || hop.Location != "/new" {
+		t.Errorf("hop = %+v, want %s/old -> /new (301)", hop, upstream.URL)
+	}
+}
+
+func TestAnalyze_RedirectLoop(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusFound)
+		case "/b":
+			http.Redirect(w, r, "/a", http.StatusFound)
+		}
+	}))
+	defer upstream.Close()
+
+	body, _ := json.Marshal(analyzeRequest{URL: upstream.URL + "/a"})
+	req := httptest.NewRequest(http.MethodPost, "/api/analyze", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	New(testConfig(t)).Analyze(rec, req)
+
+	if rec.Code != http.StatusBadGateway {
+		t.Fatalf("status = %d, want 502", rec.Code)
+	}
+	var resp errorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Code != codeRedirectLoop {
+		t.Errorf("code = %q, want %q", resp.Code, codeRedirectLoop)
+	}
+	if resp.Redirects == nil || !resp.Redirects.Loop || len(resp.Redirects.Hops) != 2 {
+		t.Fatalf("redirects = %+v, want a loop of 2 hops", resp.Redirects)
+	}
+	if hop := resp.Redirects.Hops[1]; hop.URL != upstream.URL+"/b" || hop.Location != "/a" {
+		t.Errorf("last hop = %+v, want %s/b -> /a", hop, upstream.URL)
+	}
+}
+
+func TestAnalyze_Charset(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=ISO-8859-1")
+		// "Café" in Latin-1.
+		w.Write([]byte("<html><head><meta charset=\"utf-8\"><title>Caf\xe9</title></head></html>"))
+	}))
+	defer upstream.Close()
+
+	body, _ := json.Marshal(analyzeRequest{URL: upstream.URL})
+	req := httptest.NewRequest(http.MethodPost, "/api/analyze", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	New(testConfig(t)).Analyze(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	var resp analyzer.AnalyzeResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Title != "Café" {
+		t.Errorf("Title = %q, want Café", resp.Title)
+	}
+	enc := resp.Encoding
+	if enc.Detected != "windows-1252" || enc.Source != analyzer.EncodingFromHeader || !enc.Mismatch {
+		t.Errorf("Encoding = %+v, want windows-1252 from header with mismatch", enc)
+	}
+}
+
+func TestAnalyze_UnsupportedMediaType(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{name: "pdf", contentType: "application/pdf", body: "%PDF-1.7\n"},
+		{name: "json", contentType: "application/json", body: `{"title": "x"}`},
+		{name: "pdf served as html", contentType: "text/html", body: "%PDF-1.7\n"},
+		{name: "plain text", contentType: "text/plain", body: "just text"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Write([]byte(tt.body))
+			}))
+			defer upstream.Close()
+
+			body, _ := json.Marshal(analyzeRequest{URL: upstream.URL})
+			req := httptest.NewRequest(http.MethodPost, "/api/analyze", bytes.NewReader(body))
+			rec := httptest.NewRecorder()
+
+			New(testConfig(t)).Analyze(rec, req)
+
+			if rec.Code != http.StatusUnsupportedMediaType {
+				t.Fatalf("status = %d, want 415", rec.Code)
+			}
+			var resp errorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Code != codeUnsupportedMediaType {
+				t.Errorf("code = %q, want %q", resp.Code, codeUnsupportedMediaType)
+			}
+		})
+	}
+}
+
+func TestAnalyze_XHTML(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(`<?xml version="1.0"?>
+<html xmlns="http://www.w3.org/1999/xhtml"><head><title>X</title></head>
+<body><p>unclosed</body></html>`))
+	}))
+	defer upstream.Close()
+
+	body, _ := json.Marshal(analyzeRequest{URL: upstream.URL})
+	req := httptest.NewRequest(http.MethodPost, "/api/analyze", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	New(testConfig(t)).Analyze(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	var resp analyzer.AnalyzeResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Title != "X" {
+		t.Errorf("Title = %q, want X", resp.Title)
+	}
+	if resp.XHTML == nil || resp.XHTML.WellFormed || len(resp.XHTML.Errors) != 1 {
+		t.Fatalf("XHTML = %+v, want one well-formedness error", resp.XHTML)
+	}
+	if line := resp.XHTML.Errors[0].Line; line != 3 {
+		t.Errorf("error line = %d, want 3", line)
+	}
+}
+
+func TestAnalyze_Transfer(t *testing.T) {
+	page := `<html><head><title>Gzipped</title></head><body>` + strings.Repeat("<p>filler</p>", 500) + `</body></html>`
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "br") {
+			t.Errorf("Accept-Encoding = %q, want br offered", r.Header.Get("Accept-Encoding"))
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte(page))
+		gz.Close()
+	}))
+	defer upstream.Close()
+
+	resp := analyzeOK(t, upstream.URL)
+
+	if resp.Title != "Gzipped" {
+		t.Errorf("Title = %q, want Gzipped", resp.Title)
+	}
+	tr := resp.Transfer
+	if tr == nil {
+		t.Fatal("Transfer = nil")
+	}
+	if tr.ContentEncoding != "gzip" || tr.DecompressedBytes != int64(len(page)) || tr.Truncated {
+		t.Errorf("Transfer = %+v, want gzip, %d bytes, not truncated", tr, len(page))
+	}
+	if tr.CompressedBytes == 0 || tr.CompressedBytes >= tr.DecompressedBytes {
+		t.Errorf("CompressedBytes = %d, want between 0 and %d", tr.CompressedBytes, tr.DecompressedBytes)
+	}
+	if tr.DownloadMS < tr.TTFBMS {
+		t.Errorf("DownloadMS = %d, want >= TTFBMS %d", tr.DownloadMS, tr.TTFBMS)
+	}
+}
+
+func TestAnalyze_Truncated(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><head><title>Big</title></head><body>"))
+		w.Write(bytes.Repeat([]byte("a"), maxBodyBytes))
+	}))
+	defer upstream.Close()
+
+	resp := analyzeOK(t, upstream.URL)
+
+	if resp.Transfer == nil || !resp.Transfer.Truncated || resp.Transfer.DecompressedBytes != maxBodyBytes {
+		t.Errorf("Transfer = %+v, want truncated at %d bytes", resp.Transfer, maxBodyBytes)
+	}
+	if resp.Title != "Big" {
+		t.Errorf("Title = %q, want Big", resp.Title)
+	}
+}
+
+// analyzeOK analyzes pageURL and fails the test unless it succeeds.
+func analyzeOK(t *testing.T, pageURL string) analyzer.AnalyzeResponse {
+	t.Helper()
+	body, _ := json.Marshal(analyzeRequest{URL: pageURL})
+	req := httptest.NewRequest(http.MethodPost, "/api/analyze", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	New(testConfig(t)).Analyze(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	var resp analyzer.AnalyzeResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return resp
+}
+
+func TestAnalyze_BlockedTarget(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("blocked upstream was contacted")
+	}))
+	defer upstream.Close()
+
+	body, _ := json.Marshal(analyzeRequest{URL: upstream.URL})
+	req := httptest.NewRequest(http.MethodPost, "/api/analyze", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	New(Config{}).Analyze(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want 403", rec.Code)
+	}
+	var resp errorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Code != codeTargetBlocked {
+		t.Errorf("code = %q, want %q", resp.Code, codeTargetBlocked)
+	}
+}
+
+func TestAnalyze_BlockedLinks(t *testing.T) {
+	// The page is allowlisted by hostname only, so its links to the same
+	// server by IP address are refused.
+	var upstream *httptest.Server
+	upstream = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`<html><body><a href="` + upstream.URL + `/private">internal</a></body></html>`))
+		default:
+			t.Errorf("blocked link %s was requested", r.URL.Path)
+		}
+	}))
+	defer upstream.Close()
+
+	guard, err := netguard.New([]string{"localhost"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pageURL := strings.Replace(upstream.URL, "127.0.0.1", "localhost", 1)
+	body, _ := json.Marshal(analyzeRequest{URL: pageURL})
+	req := httptest.NewRequest(http.MethodPost, "/api/analyze", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	New(Config{Guard: guard}).Analyze(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	var resp analyzer.AnalyzeResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Links) != 1 || resp.Links[0].Error != analyzer.ErrorBlocked {
+		t.Errorf("Links = %+v, want one blocked link", resp.Links)
+	}
+}
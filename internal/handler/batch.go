@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moustafa/home24/internal/analyzer"
+)
+
+const (
+	// maxBatchURLs is the most URLs a batch may hold.
+	maxBatchURLs = 1000
+	// maxBatchBodyBytes bounds the size of a batch request body.
+	maxBatchBodyBytes = 1 << 20
+)
+
+// batchResult is the line of a batch response for one URL.
+type batchResult struct {
+	Type string `json:"type"`
+	// Index is the position of URL in the batch; lines are written in the
+	// order the analyses finish.
+	Index      int                       `json:"index"`
+	URL        string                    `json:"url"`
+	Result     *analyzer.AnalyzeResponse `json:"result,omitempty"`
+	Error      *errorResponse            `json:"error,omitempty"`
+	DurationMS int64                     `json:"durationMs"`
+}
+
+// batchSummary is the last line of a batch response.
+type batchSummary struct {
+	Type string `json:"type"`
+	// Total is the number of URLs analyzed, fewer than the batch holds when
+	// the request ended early.
+	Total     int `json:"total"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	// Links counts the distinct URLs checked across all pages and the checks
+	// answered from the shared cache.
+	Links      analyzer.CacheStats `json:"links"`
+	DurationMS int64               `json:"durationMs"`
+}
+
+// Batch analyzes every URL of the request body, given as a JSON array of
+// strings or one URL per line, and streams one NDJSON line per URL as its
+// analysis finishes, followed by a summary line. Pages of all batches and
+// crawls share a budget of Config.BatchConcurrency analyses at once; the
+// link checks of one batch share a cache, the LinkChecker's worker budget
+// and its per-host limits. The scope query parameter sets the link scope
+// mode.
+func (h *Handler) Batch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBatchBodyBytes+1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "reading body failed")
+		return
+	}
+	if len(body) > maxBatchBodyBytes {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("batch body exceeds %d bytes", maxBatchBodyBytes))
+		return
+	}
+	urls, err := parseBatch(body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	scope := analyzer.Scope{Mode: analyzer.ScopeMode(r.URL.Query().Get("scope"))}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	var mu sync.Mutex
+	enc := json.NewEncoder(w)
+	write := func(v any) {
+		if err := enc.Encode(v); err != nil {
+			log.Printf("error encoding batch line: %v", err)
+		}
+		flusher.Flush()
+	}
+
+	ctx := r.Context()
+	checker := h.cfg.LinkChecker.Shared()
+	summary := batchSummary{Type: "summary"}
+	attempted := 0
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		if !h.acquireBatchSlot(ctx) {
+			break
+		}
+		attempted++
+		wg.Go(func() {
+			defer func() { <-h.batchSlots }()
+
+			pageStart := time.Now()
+			result, errResp := h.analyze(ctx, analyzeRequest{URL: u, Scope: scope}, checker, nil)
+			line := batchResult{
+				Type:       "result",
+				Index:      i,
+				URL:        u,
+				Result:     result,
+				Error:      errResp,
+				DurationMS: time.Since(pageStart).Milliseconds(),
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if errResp != nil {
+				summary.Failed++
+			} else {
+				summary.Succeeded++
+			}
+			write(line)
+		})
+	}
+	wg.Wait()
+
+	summary.Total = attempted
+	summary.Links = checker.CacheStats()
+	summary.DurationMS = time.Since(start).Milliseconds()
+	write(summary)
+}
+
+// acquireBatchSlot waits for one of the BatchConcurrency page slots and
+// reports whether it got one before ctx was done.
+func (h *Handler) acquireBatchSlot(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	select {
+	case h.batchSlots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// parseBatch returns the URLs of a batch body: a JSON array of strings, or
+// one URL per line with blank lines and lines starting with # skipped.
+func parseBatch(body []byte) ([]string, error) {
+	var urls []string
+	body = bytes.TrimSpace(body)
+	if bytes.HasPrefix(body, []byte("[")) {
+		if err := json.Unmarshal(body, &urls); err != nil {
+			return nil, errors.New("batch must be a JSON array of URL strings")
+		}
+	} else {
+		for line := range strings.Lines(string(body)) {
+			line = strings.TrimSpace(line)
+			if line != "" && !strings.HasPrefix(line, "#") {
+				urls = append(urls, line)
+			}
+		}
+	}
+
+	switch {
+	case len(urls) == 0:
+		return nil, errors.New("batch holds no URLs")
+	case len(urls) > maxBatchURLs:
+		return nil, fmt.Errorf("batch holds %d URLs, at most %d are allowed", len(urls), maxBatchURLs)
+	}
+	return urls, nil
+}
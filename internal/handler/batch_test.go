@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// batchLine holds the fields of both batch result and summary lines.
+type batchLine struct {
+	batchResult
+	Total     int `json:"total"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	Links     struct {
+		URLs int `json:"urls"`
+		Hits int `json:"hits"`
+	} `json:"links"`
+}
+
+func batch(t *testing.T, body string) (*httptest.ResponseRecorder, []batchLine) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/batch", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	New(testConfig(t)).Batch(rec, req)
+
+	var lines []batchLine
+	if rec.Code != http.StatusOK {
+		return rec, nil
+	}
+	sc := bufio.NewScanner(rec.Body)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		var line batchLine
+		if err := json.Unmarshal(sc.Bytes(), &line); err != nil {
+			t.Fatalf("invalid NDJSON line %q: %v", sc.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	return rec, lines
+}
+
+func TestBatch(t *testing.T) {
+	var shared atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/shared", func(w http.ResponseWriter, r *http.Request) { shared.Add(1) })
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<html><head><title>%s</title></head><body><a href="/shared">Shared</a></body></html>`, r.URL.Path)
+	})
+	upstream := httptest.NewServer(mux)
+	defer upstream.Close()
+
+	for _, tc := range []struct {
+		name string
+		body string
+	}{
+		{"json", fmt.Sprintf(`["%[1]s/one", "not-a-url", "%[1]s/two"]`, upstream.URL)},
+		{"lines", fmt.Sprintf("# landing pages\n%[1]s/one\n\nnot-a-url\n%[1]s/two\n", upstream.URL)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			shared.Store(0)
+			rec, lines := batch(t, tc.body)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200", rec.Code)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+				t.Errorf("Content-Type = %q, want application/x-ndjson", ct)
+			}
+			if len(lines) != 4 {
+				t.Fatalf("got %d lines, want 3 results and a summary", len(lines))
+			}
+
+			results := lines[:3]
+			slices.SortFunc(results, func(a, b batchLine) int { return a.Index - b.Index })
+			if r := results[0]; r.Type != "result" || r.Result == nil || r.Result.Title != "/one" {
+				t.Errorf("line for /one = %+v, want its result", r.batchResult)
+			}
+			if r := results[1]; r.Error == nil || r.Error.StatusCode != http.StatusBadRequest {
+				t.Errorf("line for not-a-url = %+v, want a 400 error", r.batchResult)
+			}
+			if r := results[2]; r.Result == nil || r.Result.InternalLinks != 1 {
+				t.Errorf("line for /two = %+v, want its result", r.batchResult)
+			}
+
+			summary := lines[3]
+			if summary.Type != "summary" || summary.Total != 3 || summary.Succeeded != 2 || summary.Failed != 1 {
+				t.Errorf("summary = %+v, want 3 total, 2 succeeded, 1 failed", summary)
+			}
+			if summary.Links.URLs != 1 || summary.Links.Hits != 1 {
+				t.Errorf("summary links = %+v, want 1 URL and 1 cache hit", summary.Links)
+			}
+			if got := shared.Load(); got != 1 {
+				t.Errorf("server saw %d requests for /shared, want 1", got)
+			}
+		})
+	}
+}
+
+func TestBatch_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequestWithContext(ctx, http.MethodPost, "/api/batch", strings.NewReader("https://example.com/a\nhttps://example.com/b"))
+	rec := httptest.NewRecorder()
+
+	New(testConfig(t)).Batch(rec, req)
+
+	var summary batchLine
+	if err := json.Unmarshal(rec.Body.Bytes(), &summary); err != nil {
+		t.Fatalf("invalid summary %q: %v", rec.Body, err)
+	}
+	if summary.Type != "summary" || summary.Total != 0 {
+		t.Errorf("summary = %+v, want a total of 0 for a request canceled before any URL", summary)
+	}
+}
+
+func TestBatch_InvalidBody(t *testing.T) {
+	tooMany := strings.Repeat("https://example.com/\n", maxBatchURLs+1)
+	for name, body := range map[string]string{
+		"empty":       "\n# nothing\n",
+		"bad json":    `["https://example.com/", 1]`,
+		"too many":    tooMany,
+		"empty array": "[]",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if rec, _ := batch(t, body); rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400", rec.Code)
+			}
+		})
+	}
+}
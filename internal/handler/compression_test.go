@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestDecodeBody(t *testing.T) {
+	const page = "<html><head><title>Compressed</title></head></html>"
+
+	compress := func(newWriter func(io.Writer) io.WriteCloser) []byte {
+		var buf bytes.Buffer
+		w := newWriter(&buf)
+		w.Write([]byte(page))
+		w.Close()
+		return buf.Bytes()
+	}
+	gz := compress(func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) })
+	// "gzip, br" means gzip was applied first, then brotli.
+	var stacked bytes.Buffer
+	bw := brotli.NewWriter(&stacked)
+	bw.Write(gz)
+	bw.Close()
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+	}{
+		{"identity", "", []byte(page)},
+		{"gzip", "gzip", gz},
+		{"zlib deflate", "deflate", compress(func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) })},
+		{"raw deflate", "deflate", compress(func(w io.Writer) io.WriteCloser {
+			fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+			return fw
+		})},
+		{"brotli", "br", compress(func(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) })},
+		{"stacked", "gzip, br", stacked.Bytes()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := decodeBody(bytes.NewReader(tt.body), tt.encoding)
+			if err != nil {
+				t.Fatalf("decodeBody() error: %v", err)
+			}
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("reading decoded body: %v", err)
+			}
+			if string(got) != page {
+				t.Errorf("decoded = %q, want %q", got, page)
+			}
+		})
+	}
+}
+
+func TestDecodeBody_Errors(t *testing.T) {
+	if _, err := decodeBody(bytes.NewReader([]byte("x")), "compress"); err == nil {
+		t.Error("unsupported coding: got nil error")
+	}
+	if _, err := decodeBody(bytes.NewReader([]byte("not gzip at all")), "gzip"); err == nil {
+		t.Error("corrupt gzip: got nil error")
+	}
+	r, err := decodeBody(bytes.NewReader(nil), "gzip")
+	if err != nil {
+		t.Fatalf("empty gzip body: %v", err)
+	}
+	if b, _ := io.ReadAll(r); len(b) != 0 {
+		t.Errorf("empty gzip body decoded to %q", b)
+	}
+}
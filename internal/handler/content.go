@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const (
+	mediaHTML  = "text/html"
+	mediaXHTML = "application/xhtml+xml"
+)
+
+// unsupportedTypeError reports a fetched document that is neither HTML nor
+// XHTML.
+type unsupportedTypeError struct {
+	mediaType string
+}
+
+func (e *unsupportedTypeError) Error() string {
+	if e.mediaType == "" {
+		return "page has no recognizable content type"
+	}
+	return fmt.Sprintf("unsupported content type %s, only HTML and XHTML pages can be analyzed", e.mediaType)
+}
+
+// pageContentType decides how a fetched page should be analyzed from its
+// Content-Type header and a sniff of its body. It returns the header with
+// the media type replaced by text/html or application/xhtml+xml, keeping
+// any charset parameter. The header is trusted for HTML unless the body is
+// plainly binary; a missing or generic header is replaced by the sniffed
+// type.
+func pageContentType(header string, body []byte) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(header)
+	if err != nil {
+		mediaType, params = "", nil
+	}
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(body))
+
+	var resolved string
+	switch mediaType {
+	case mediaHTML, mediaXHTML:
+		if isBinaryType(sniffed) {
+			return "", &unsupportedTypeError{mediaType: sniffed}
+		}
+		resolved = mediaType
+	case "", "application/octet-stream", "text/plain":
+		switch {
+		case sniffed == mediaHTML:
+			resolved = mediaHTML
+		case isXMLType(sniffed) && isXHTML(body):
+			resolved = mediaXHTML
+		default:
+			return "", &unsupportedTypeError{mediaType: cmp.Or(mediaType, sniffed)}
+		}
+	case "application/xml", "text/xml":
+		if !isXHTML(body) {
+			return "", &unsupportedTypeError{mediaType: mediaType}
+		}
+		resolved = mediaXHTML
+	default:
+		return "", &unsupportedTypeError{mediaType: mediaType}
+	}
+	return mime.FormatMediaType(resolved, params), nil
+}
+
+func isBinaryType(mediaType string) bool {
+	return !strings.HasPrefix(mediaType, "text/") && !isXMLType(mediaType) && mediaType != "application/octet-stream"
+}
+
+func isXMLType(mediaType string) bool {
+	return mediaType == "text/xml" || mediaType == "application/xml" || strings.HasSuffix(mediaType, "+xml")
+}
+
+// isXHTML reports whether an XML document declares the XHTML namespace.
+func isXHTML(body []byte) bool {
+	return bytes.Contains(body[:min(len(body), 4096)], []byte("http://www.w3.org/1999/xhtml"))
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/moustafa/home24/internal/analyzer"
+	"github.com/moustafa/home24/internal/crawler"
+)
+
+const (
+	// maxCrawlDepth and maxCrawlPages bound the limits a crawl may ask for.
+	maxCrawlDepth = 10
+	maxCrawlPages = 500
+)
+
+// crawlRequest is an analyzeRequest for the seed page, whose options apply
+// to every crawled page, plus the crawl limits.
+type crawlRequest struct {
+	analyzeRequest
+	// MaxDepth defaults to crawler.DefaultMaxDepth when absent.
+	MaxDepth *int     `json:"maxDepth"`
+	MaxPages int      `json:"maxPages"`
+	Include  []string `json:"include"`
+	Exclude  []string `json:"exclude"`
+}
+
+// crawlPage is the line of a crawl response for one page.
+type crawlPage struct {
+	Type string `json:"type"`
+	crawler.Page
+	Error *errorResponse `json:"error,omitempty"`
+}
+
+// crawlSite is the last line of a crawl response.
+type crawlSite struct {
+	Type string `json:"type"`
+	crawler.Site
+}
+
+func (req crawlRequest) options() (crawler.Options, *errorResponse) {
+	opts := crawler.Options{
+		MaxDepth:  crawler.DefaultMaxDepth,
+		MaxPages:  req.MaxPages,
+		Include:   req.Include,
+		Exclude:   req.Exclude,
+		Normalize: req.Normalize,
+	}
+	if req.MaxDepth != nil {
+		opts.MaxDepth = *req.MaxDepth
+	}
+	if opts.MaxPages == 0 {
+		opts.MaxPages = crawler.DefaultMaxPages
+	}
+
+	switch {
+	case opts.MaxDepth < 0 || opts.MaxDepth > maxCrawlDepth:
+		return opts, newError(http.StatusBadRequest, "", fmt.Sprintf("maxDepth must be between 0 and %d", maxCrawlDepth))
+	case opts.MaxPages < 0 || opts.MaxPages > maxCrawlPages:
+		return opts, newError(http.StatusBadRequest, "", fmt.Sprintf("maxPages must be between 1 and %d", maxCrawlPages))
+	}
+	if err := crawler.ValidatePatterns(append(opts.Include, opts.Exclude...)); err != nil {
+		return opts, newError(http.StatusBadRequest, "", err.Error())
+	}
+	return opts, nil
+}
+
+// Crawl analyzes the page of the request body and the internal pages
+// reachable from it, and streams one NDJSON line per page as its analysis
+// finishes, followed by a line of site-wide aggregates. Pages count against
+// the same Config.BatchConcurrency budget as batches, and their link checks
+// share a cache.
+func (h *Handler) Crawl(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	var req crawlRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if _, errResp := req.validate(); errResp != nil {
+		writeJSON(w, errResp.StatusCode, errResp)
+		return
+	}
+	opts, errResp := req.options()
+	if errResp != nil {
+		writeJSON(w, errResp.StatusCode, errResp)
+		return
+	}
+	opts.Workers = h.cfg.BatchConcurrency
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	enc := json.NewEncoder(w)
+	write := func(v any) {
+		if err := enc.Encode(v); err != nil {
+			log.Printf("error encoding crawl line: %v", err)
+		}
+		flusher.Flush()
+	}
+
+	checker := h.cfg.LinkChecker.Shared()
+	analyze := func(ctx context.Context, pageURL string) (*analyzer.AnalyzeResponse, error) {
+		if !h.acquireBatchSlot(ctx) {
+			return nil, ctx.Err()
+		}
+		defer func() { <-h.batchSlots }()
+
+		pageReq := req.analyzeRequest
+		pageReq.URL = pageURL
+		result, errResp := h.analyze(ctx, pageReq, checker, nil)
+		switch {
+		case errResp == nil:
+			return result, nil
+		case errResp.Code == codeUnsupportedMediaType:
+			// Links to PDFs, images and other documents are not pages.
+			return nil, fmt.Errorf("%w: %w", crawler.ErrSkipped, errResp)
+		default:
+			return nil, errResp
+		}
+	}
+
+	site := crawler.Crawl(r.Context(), req.URL, opts, analyze, func(p crawler.Page) {
+		line := crawlPage{Type: "page", Page: p}
+		if p.Err != nil && !errors.As(p.Err, &line.Error) {
+			line.Error = newError(http.StatusServiceUnavailable, "", p.Err.Error())
+		}
+		write(line)
+	})
+	write(crawlSite{Type: "site", Site: site})
+}
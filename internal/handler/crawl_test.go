@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/moustafa/home24/internal/crawler"
+)
+
+// crawlLine holds the fields of both page and site lines.
+type crawlLine struct {
+	crawlPage
+	crawler.Site
+}
+
+func TestCrawl(t *testing.T) {
+	pages := map[string]string{
+		"/":       `<title>Home</title><h1>Home</h1><a href="/about">About</a><a href="/missing">Old</a><a href="/admin/">Admin</a>`,
+		"/about":  `<title>Home</title><a href="/">Home</a><a href="/missing">Old</a><a href="/deep">Deep</a>`,
+		"/admin/": `<title>Admin</title><h1>Admin</h1>`,
+		"/deep":   `<title>Deep</title><h1>Deep</h1>`,
+	}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, body)
+	}))
+	defer upstream.Close()
+
+	depth := 1
+	body, _ := json.Marshal(crawlRequest{
+		analyzeRequest: analyzeRequest{URL: upstream.URL},
+		MaxDepth:       &depth,
+		Exclude:        []string{"/admin"},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/crawl", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	New(testConfig(t)).Crawl(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	var lines []crawlLine
+	sc := bufio.NewScanner(rec.Body)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		var line crawlLine
+		if err := json.Unmarshal(sc.Bytes(), &line); err != nil {
+			t.Fatalf("invalid NDJSON line %q: %v", sc.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 2 pages and the site", len(lines))
+	}
+	for _, l := range lines[:2] {
+		if l.crawlPage.Type != "page" || l.Result == nil {
+			t.Errorf("line = %+v, want an analyzed page", l.crawlPage)
+		}
+	}
+	if lines[1].URL != upstream.URL+"/about" || lines[1].Depth != 1 || lines[1].Referrer != upstream.URL {
+		t.Errorf("second page = %s at depth %d from %s, want /about at depth 1 from the seed",
+			lines[1].URL, lines[1].Depth, lines[1].Referrer)
+	}
+
+	site := lines[2]
+	if site.crawlPage.Type != "site" || site.Pages != 2 || site.Failed != 0 {
+		t.Fatalf("site = %+v, want 2 pages", site.Site)
+	}
+	if len(site.MissingH1) != 1 || site.MissingH1[0] != upstream.URL+"/about" {
+		t.Errorf("MissingH1 = %v, want /about", site.MissingH1)
+	}
+	if len(site.DuplicateTitles) != 1 || len(site.DuplicateTitles[0].Pages) != 2 {
+		t.Errorf("DuplicateTitles = %+v, want Home on both pages", site.DuplicateTitles)
+	}
+	if len(site.BrokenLinks) != 1 || site.BrokenLinks[0].URL != upstream.URL+"/missing" ||
+		len(site.BrokenLinks[0].Pages) != 2 {
+		t.Errorf("BrokenLinks = %+v, want /missing referenced by both pages", site.BrokenLinks)
+	}
+}
+
+func TestCrawl_SkipsNonHTML(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/doc.pdf" {
+			w.Header().Set("Content-Type", "application/pdf")
+			fmt.Fprint(w, "%PDF-1.7")
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<title>Home</title><h1>Home</h1><a href="/doc.pdf">Report</a>`)
+	}))
+	defer upstream.Close()
+
+	body, _ := json.Marshal(crawlRequest{analyzeRequest: analyzeRequest{URL: upstream.URL}})
+	req := httptest.NewRequest(http.MethodPost, "/api/crawl", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	New(testConfig(t)).Crawl(rec, req)
+
+	var lines []crawlLine
+	sc := bufio.NewScanner(rec.Body)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		var line crawlLine
+		if err := json.Unmarshal(sc.Bytes(), &line); err != nil {
+			t.Fatalf("invalid NDJSON line %q: %v", sc.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 2 pages and the site", len(lines))
+	}
+	pdf := lines[1]
+	if pdf.Error == nil || pdf.Error.Code != codeUnsupportedMediaType {
+		t.Errorf("PDF line = %+v, want the %s error", pdf.crawlPage, codeUnsupportedMediaType)
+	}
+	if site := lines[2]; site.Pages != 1 || site.Failed != 0 || site.Skipped != 1 {
+		t.Errorf("site = %+v, want 1 page and 1 skipped", site.Site)
+	}
+}
+
+func TestCrawl_InvalidRequest(t *testing.T) {
+	depth := maxCrawlDepth + 1
+	for name, req := range map[string]crawlRequest{
+		"url":      {analyzeRequest: analyzeRequest{URL: "not-a-url"}},
+		"depth":    {analyzeRequest: analyzeRequest{URL: "https://example.com"}, MaxDepth: &depth},
+		"pages":    {analyzeRequest: analyzeRequest{URL: "https://example.com"}, MaxPages: maxCrawlPages + 1},
+		"patterns": {analyzeRequest: analyzeRequest{URL: "https://example.com"}, Include: []string{"/[a"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			body, _ := json.Marshal(req)
+			r := httptest.NewRequest(http.MethodPost, "/api/crawl", bytes.NewReader(body))
+			rec := httptest.NewRecorder()
+
+			New(testConfig(t)).Crawl(rec, r)
+
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400", rec.Code)
+			}
+		})
+	}
+}
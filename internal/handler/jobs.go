@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/moustafa/home24/internal/jobs"
+)
+
+// jobResponse is a job as reported by the job API: result holds the
+// AnalyzeResponse once done, and error the errorResponse once failed.
+type jobResponse struct {
+	jobs.Snapshot
+	Error *errorResponse `json:"error,omitempty"`
+}
+
+func newJobResponse(snap jobs.Snapshot) jobResponse {
+	resp := jobResponse{Snapshot: snap}
+	if snap.Err != nil && !errors.As(snap.Err, &resp.Error) {
+		resp.Error = newError(http.StatusInternalServerError, "", snap.Err.Error())
+	}
+	return resp
+}
+
+// CreateJob queues the analysis described by the request body, which takes
+// the same fields as Analyze, and answers 202 with the queued job. The
+// analysis runs independently of the request.
+func (h *Handler) CreateJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req analyzeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if _, errResp := req.validate(); errResp != nil {
+		writeJSON(w, errResp.StatusCode, errResp)
+		return
+	}
+
+	snap, err := h.jobManager().Submit(func(ctx context.Context) (any, error) {
+		result, errResp := h.analyze(ctx, req, h.cfg.LinkChecker, nil)
+		if errResp != nil {
+			return nil, errResp
+		}
+		return result, nil
+	})
+	switch {
+	case errors.Is(err, jobs.ErrQueueFull):
+		w.Header().Set("Retry-After", "5")
+		writeJSON(w, http.StatusServiceUnavailable, newError(http.StatusServiceUnavailable, codeQueueFull, err.Error()))
+		return
+	case err != nil:
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+
+	w.Header().Set("Location", "/api/jobs/"+snap.ID)
+	writeJSON(w, http.StatusAccepted, newJobResponse(snap))
+}
+
+// Job reports the job named by the id path value on GET and cancels it on
+// DELETE.
+func (h *Handler) Job(w http.ResponseWriter, r *http.Request) {
+	var snap jobs.Snapshot
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		snap, err = h.jobManager().Get(r.PathValue("id"))
+	case http.MethodDelete:
+		snap, err = h.jobManager().Cancel(r.PathValue("id"))
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	switch {
+	case errors.Is(err, jobs.ErrNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, jobs.ErrFinished):
+		writeJSON(w, http.StatusConflict, newError(http.StatusConflict, codeJobFinished, err.Error()))
+	default:
+		writeJSON(w, http.StatusOK, newJobResponse(snap))
+	}
+}
@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/moustafa/home24/internal/analyzer"
+	"github.com/moustafa/home24/internal/jobs"
+)
+
+// jobResult is a jobResponse with its result decoded.
+type jobResult struct {
+	ID     string                    `json:"id"`
+	Status jobs.Status               `json:"status"`
+	Result *analyzer.AnalyzeResponse `json:"result"`
+	Error  *errorResponse            `json:"error"`
+}
+
+func jobHandler(t *testing.T, cfg jobs.Config) *Handler {
+	t.Helper()
+	m := jobs.New(cfg)
+	t.Cleanup(func() { m.Close(context.Background()) })
+	c := testConfig(t)
+	c.Jobs = m
+	return New(c)
+}
+
+func createJob(t *testing.T, h *Handler, pageURL string) *httptest.ResponseRecorder {
+	t.Helper()
+	body, _ := json.Marshal(analyzeRequest{URL: pageURL})
+	req := httptest.NewRequest(http.MethodPost, "/api/jobs", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.CreateJob(rec, req)
+	return rec
+}
+
+func jobRequest(t *testing.T, h *Handler, method, id string) (int, jobResult) {
+	t.Helper()
+	req := httptest.NewRequest(method, "/api/jobs/"+id, nil)
+	req.SetPathValue("id", id)
+	rec := httptest.NewRecorder()
+	h.Job(rec, req)
+
+	var job jobResult
+	if rec.Code == http.StatusOK {
+		if err := json.NewDecoder(rec.Body).Decode(&job); err != nil {
+			t.Fatalf("failed to decode job: %v", err)
+		}
+	}
+	return rec.Code, job
+}
+
+// pollJob polls the job until it has a final status.
+func pollJob(t *testing.T, h *Handler, id string) jobResult {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		code, job := jobRequest(t, h, http.MethodGet, id)
+		if code != http.StatusOK {
+			t.Fatalf("status = %d, want 200", code)
+		}
+		if job.Status.Finished() {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return jobResult{}
+}
+
+func TestJobs_Lifecycle(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><title>Queued</title></head></html>`))
+	}))
+	defer upstream.Close()
+	h := jobHandler(t, jobs.Config{})
+
+	rec := createJob(t, h, upstream.URL)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want 202", rec.Code)
+	}
+	var created jobResult
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	if loc := rec.Header().Get("Location"); loc != "/api/jobs/"+created.ID {
+		t.Errorf("Location = %q, want /api/jobs/%s", loc, created.ID)
+	}
+
+	job := pollJob(t, h, created.ID)
+	if job.Status != jobs.StatusDone || job.Result == nil || job.Result.Title != "Queued" {
+		t.Errorf("job = %s with %+v, want done with title %q", job.Status, job.Result, "Queued")
+	}
+
+	if code, _ := jobRequest(t, h, http.MethodDelete, created.ID); code != http.StatusConflict {
+		t.Errorf("DELETE finished job: status = %d, want 409", code)
+	}
+	if code, _ := jobRequest(t, h, http.MethodGet, "unknown"); code != http.StatusNotFound {
+		t.Errorf("GET unknown job: status = %d, want 404", code)
+	}
+}
+
+func TestJobs_Failed(t *testing.T) {
+	h := jobHandler(t, jobs.Config{})
+
+	rec := createJob(t, h, "http://localhost:1")
+	var created jobResult
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+
+	job := pollJob(t, h, created.ID)
+	if job.Status != jobs.StatusFailed || job.Error == nil || job.Error.StatusCode != http.StatusBadGateway {
+		t.Errorf("job = %s with %+v, want failed with 502", job.Status, job.Error)
+	}
+}
+
+func TestJobs_InvalidURL(t *testing.T) {
+	rec := createJob(t, jobHandler(t, jobs.Config{}), "not-a-url")
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+}
+
+func TestJobs_CancelAndQueueFull(t *testing.T) {
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer upstream.Close()
+	defer close(release)
+	h := jobHandler(t, jobs.Config{Workers: 1, QueueDepth: 1})
+
+	var ids []string
+	for range 2 {
+		rec := createJob(t, h, upstream.URL)
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("status = %d, want 202", rec.Code)
+		}
+		var created jobResult
+		json.NewDecoder(rec.Body).Decode(&created)
+		ids = append(ids, created.ID)
+		// Let the worker pick up the first job before queuing the second.
+		for {
+			if _, job := jobRequest(t, h, http.MethodGet, ids[0]); job.Status == jobs.StatusRunning {
+				break
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	rec := createJob(t, h, upstream.URL)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", rec.Code)
+	}
+	var resp errorResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.Code != codeQueueFull {
+		t.Errorf("code = %q, want %q", resp.Code, codeQueueFull)
+	}
+
+	for _, id := range ids {
+		code, job := jobRequest(t, h, http.MethodDelete, id)
+		if code != http.StatusOK || job.Status != jobs.StatusCanceled {
+			t.Errorf("DELETE %s: status = %d, job %s; want 200, canceled", id, code, job.Status)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/moustafa/home24/internal/analyzer"
+)
+
+// Stream events besides the analyzer's phases. Failures are not sent as
+// "error", which EventSource reserves for connection errors.
+const (
+	eventFetched = "fetched"
+	eventDone    = "done"
+	eventFailed  = "failed"
+)
+
+// fetchedEvent is sent once the page has been downloaded.
+type fetchedEvent struct {
+	URL         string                   `json:"url"`
+	StatusCode  int                      `json:"statusCode"`
+	ContentType string                   `json:"contentType"`
+	Transfer    analyzer.TransferMetrics `json:"transfer"`
+}
+
+// Stream analyzes the page given by the url query parameter like Analyze,
+// reporting progress as server-sent events: fetched, parsed, static with the
+// partial result, links after each checked URL, and finally done with the
+// full result or failed with an errorResponse. The options query parameter
+// holds the other fields of an Analyze request body as JSON; the scope query
+// parameter, if set, overrides the scope mode.
+func (h *Handler) Stream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(event string, data any) {
+		b, err := json.Marshal(data)
+		if err != nil {
+			log.Printf("error encoding %s event: %v", event, err)
+			return
+		}
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, b)
+		flusher.Flush()
+	}
+
+	q := r.URL.Query()
+	var req analyzeRequest
+	if opts := q.Get("options"); opts != "" {
+		if err := json.Unmarshal([]byte(opts), &req); err != nil {
+			send(eventFailed, newError(http.StatusBadRequest, "", "invalid options JSON"))
+			return
+		}
+	}
+	req.URL = q.Get("url")
+	if mode := q.Get("scope"); mode != "" {
+		req.Scope.Mode = analyzer.ScopeMode(mode)
+	}
+
+	result, errResp := h.analyze(r.Context(), req, h.cfg.LinkChecker, send)
+	if errResp != nil {
+		send(eventFailed, errResp)
+		return
+	}
+	send(eventDone, result)
+}
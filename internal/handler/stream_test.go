@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/moustafa/home24/internal/analyzer"
+)
+
+type sseEvent struct {
+	name string
+	data string
+}
+
+func readEvents(t *testing.T, body string) []sseEvent {
+	t.Helper()
+	var events []sseEvent
+	var ev sseEvent
+	sc := bufio.NewScanner(strings.NewReader(body))
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case line == "":
+			events = append(events, ev)
+			ev = sseEvent{}
+		case strings.HasPrefix(line, "event: "):
+			ev.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			ev.data = strings.TrimPrefix(line, "data: ")
+		default:
+			t.Fatalf("unexpected line %q", line)
+		}
+	}
+	return events
+}
+
+func stream(t *testing.T, pageURL string) []sseEvent {
+	t.Helper()
+	return streamQuery(t, url.Values{"url": {pageURL}})
+}
+
+func streamQuery(t *testing.T, q url.Values) []sseEvent {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/analyze/stream?"+q.Encode(), nil)
+	rec := httptest.NewRecorder()
+
+	New(testConfig(t)).Stream(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", ct)
+	}
+	return readEvents(t, rec.Body.String())
+}
+
+func TestStream_Events(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><title>Stream</title></head><body>
+			<a href="/a">A</a><a href="/b">B</a><a href="/a">A again</a></body></html>`))
+	})
+	mux.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/b", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	upstream := httptest.NewServer(mux)
+	defer upstream.Close()
+
+	events := stream(t, upstream.URL+"/")
+
+	var names []string
+	for _, ev := range events {
+		names = append(names, ev.name)
+	}
+	want := []string{"fetched", "parsed", "static", "links", "links", "done"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Fatalf("events = %v, want %v", names, want)
+	}
+
+	var static analyzer.Progress
+	if err := json.Unmarshal([]byte(events[2].data), &static); err != nil {
+		t.Fatal(err)
+	}
+	if static.Result == nil || static.Result.Title != "Stream" {
+		t.Errorf("static result = %+v, want title %q", static.Result, "Stream")
+	}
+	if static.Result != nil && len(static.Result.Links) != 0 {
+		t.Errorf("static result has %d links, want none yet", len(static.Result.Links))
+	}
+
+	var last analyzer.Progress
+	if err := json.Unmarshal([]byte(events[4].data), &last); err != nil {
+		t.Fatal(err)
+	}
+	if last.Checked != 2 || last.Total != 2 {
+		t.Errorf("last links event = %d/%d, want 2/2", last.Checked, last.Total)
+	}
+
+	var done analyzer.AnalyzeResponse
+	if err := json.Unmarshal([]byte(events[5].data), &done); err != nil {
+		t.Fatal(err)
+	}
+	if done.InaccessibleLinks != 1 || done.Transfer == nil {
+		t.Errorf("done: inaccessible = %d, transfer = %v; want 1 and metrics", done.InaccessibleLinks, done.Transfer)
+	}
+}
+
+func TestStream_Error(t *testing.T) {
+	events := stream(t, "not-a-url")
+
+	if len(events) != 1 || events[0].name != eventFailed {
+		t.Fatalf("events = %+v, want a single failed event", events)
+	}
+	var resp errorResponse
+	if err := json.Unmarshal([]byte(events[0].data), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("statusCode = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestStream_Options(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><title>Test Page</title></head></html>`))
+	}))
+	defer upstream.Close()
+
+	events := streamQuery(t, url.Values{
+		"url":     {upstream.URL},
+		"options": {`{"metadataLimits": {"titleMax": 5}}`},
+	})
+
+	last := events[len(events)-1]
+	if last.name != eventDone {
+		t.Fatalf("last event = %+v, want done", last)
+	}
+	var done analyzer.AnalyzeResponse
+	if err := json.Unmarshal([]byte(last.data), &done); err != nil {
+		t.Fatal(err)
+	}
+	if got := done.Metadata.TitleLength.Max; got != 5 {
+		t.Errorf("TitleLength.Max = %d, want 5 from options", got)
+	}
+
+	events = streamQuery(t, url.Values{"url": {upstream.URL}, "options": {"{"}})
+	if len(events) != 1 || events[0].name != eventFailed {
+		t.Errorf("events = %+v, want a single failed event for invalid options", events)
+	}
+}
+
+func TestStream_MethodNotAllowed(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/analyze/stream", nil)
+	rec := httptest.NewRecorder()
+
+	New(testConfig(t)).Stream(rec, req)
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want 405", rec.Code)
+	}
+}
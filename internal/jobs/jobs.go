@@ -0,0 +1,292 @@
+// Package jobs runs work in the background on a bounded pool of workers.
+//
+// A Manager queues submitted jobs up to a fixed depth and runs them with a
+// context of their own, so they outlive the request that submitted them.
+// Finished jobs are kept for a TTL so their results can be fetched, then
+// forgotten.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Status is the state of a job.
+type Status string
+
+const (
+	StatusQueued   Status = "queued"
+	StatusRunning  Status = "running"
+	StatusDone     Status = "done"
+	StatusFailed   Status = "failed"
+	StatusCanceled Status = "canceled"
+)
+
+// Finished reports whether s is a final status.
+func (s Status) Finished() bool {
+	return s == StatusDone || s == StatusFailed || s == StatusCanceled
+}
+
+// Defaults for the zero fields of Config.
+const (
+	DefaultWorkers    = 4
+	DefaultQueueDepth = 100
+	DefaultTTL        = 15 * time.Minute
+)
+
+var (
+	// ErrQueueFull is returned by Submit when QueueDepth jobs are waiting.
+	ErrQueueFull = errors.New("job queue is full")
+	// ErrClosed is returned by Submit after Close.
+	ErrClosed = errors.New("job manager is closed")
+	// ErrNotFound is returned for unknown and expired job ids.
+	ErrNotFound = errors.New("job not found")
+	// ErrFinished is returned by Cancel for a job that already finished.
+	ErrFinished = errors.New("job already finished")
+)
+
+// Config sizes a Manager.
+type Config struct {
+	// Workers is the number of jobs run at once.
+	Workers int
+	// QueueDepth is the number of jobs that may wait for a worker.
+	QueueDepth int
+	// TTL is how long a finished job is kept.
+	TTL time.Duration
+}
+
+// Func is the work of a job. It should return promptly once ctx is done.
+type Func func(ctx context.Context) (any, error)
+
+// Snapshot is the state of a job at one point in time.
+type Snapshot struct {
+	ID         string    `json:"id"`
+	Status     Status    `json:"status"`
+	CreatedAt  time.Time `json:"createdAt"`
+	StartedAt  time.Time `json:"startedAt,omitzero"`
+	FinishedAt time.Time `json:"finishedAt,omitzero"`
+	// ExpiresAt is when a finished job is forgotten.
+	ExpiresAt time.Time `json:"expiresAt,omitzero"`
+	// Result and Err are what the job's Func returned, once done or failed.
+	Result any   `json:"result,omitempty"`
+	Err    error `json:"-"`
+}
+
+type job struct {
+	snap   Snapshot
+	fn     Func
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Manager runs jobs. It must be created with New and stopped with Close.
+type Manager struct {
+	cfg Config
+	// ctx is the parent of every job's context; Close cancels it.
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu sync.Mutex
+	// queue holds the queued jobs in submission order; ready is signaled
+	// when a job is added and broadcast on Close.
+	queue  []*job
+	ready  *sync.Cond
+	jobs   map[string]*job
+	closed bool
+}
+
+// New returns a Manager for cfg and starts its workers.
+func New(cfg Config) *Manager {
+	if cfg.Workers <= 0 {
+		cfg.Workers = DefaultWorkers
+	}
+	if cfg.QueueDepth <= 0 {
+		cfg.QueueDepth = DefaultQueueDepth
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = DefaultTTL
+	}
+
+	m := &Manager{
+		cfg:  cfg,
+		jobs: make(map[string]*job),
+	}
+	m.ready = sync.NewCond(&m.mu)
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+
+	for range cfg.Workers {
+		m.wg.Go(m.work)
+	}
+	m.wg.Go(m.sweep)
+	return m
+}
+
+// Submit queues fn and returns the new job.
+func (m *Manager) Submit(fn Func) (Snapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return Snapshot{}, ErrClosed
+	}
+	if len(m.queue) >= m.cfg.QueueDepth {
+		return Snapshot{}, ErrQueueFull
+	}
+
+	j := &job{
+		snap: Snapshot{ID: rand.Text(), Status: StatusQueued, CreatedAt: time.Now()},
+		fn:   fn,
+	}
+	j.ctx, j.cancel = context.WithCancel(m.ctx)
+	m.queue = append(m.queue, j)
+	m.jobs[j.snap.ID] = j
+	m.ready.Signal()
+	return j.snap, nil
+}
+
+// Get returns the job with the given id.
+func (m *Manager) Get(id string) (Snapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.lookup(id, time.Now())
+	if !ok {
+		return Snapshot{}, ErrNotFound
+	}
+	return j.snap, nil
+}
+
+// Cancel cancels a queued or running job. A queued job leaves the queue at
+// once. A running job is marked canceled at once; its Func is left to notice
+// the canceled context.
+func (m *Manager) Cancel(id string) (Snapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	j, ok := m.lookup(id, now)
+	if !ok {
+		return Snapshot{}, ErrNotFound
+	}
+	if j.snap.Status.Finished() {
+		return j.snap, ErrFinished
+	}
+	if j.snap.Status == StatusQueued {
+		m.queue = slices.DeleteFunc(m.queue, func(q *job) bool { return q == j })
+	}
+	j.cancel()
+	m.finish(j, StatusCanceled, now)
+	return j.snap, nil
+}
+
+// Close stops accepting jobs, cancels the queued and running ones and waits
+// for the workers to return or ctx to be done.
+func (m *Manager) Close(ctx context.Context) error {
+	m.mu.Lock()
+	if !m.closed {
+		m.closed = true
+		m.cancel()
+		now := time.Now()
+		for _, j := range m.queue {
+			m.finish(j, StatusCanceled, now)
+		}
+		m.queue = nil
+		m.ready.Broadcast()
+	}
+	m.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// lookup returns the job with the given id unless it expired, which it
+// forgets. m.mu must be held.
+func (m *Manager) lookup(id string, now time.Time) (*job, bool) {
+	j, ok := m.jobs[id]
+	if ok && j.expired(now) {
+		delete(m.jobs, id)
+		return nil, false
+	}
+	return j, ok
+}
+
+// finish moves j to a final status. m.mu must be held.
+func (m *Manager) finish(j *job, status Status, now time.Time) {
+	j.snap.Status = status
+	j.snap.FinishedAt = now
+	j.snap.ExpiresAt = now.Add(m.cfg.TTL)
+}
+
+func (j *job) expired(now time.Time) bool {
+	return j.snap.Status.Finished() && !now.Before(j.snap.ExpiresAt)
+}
+
+func (m *Manager) work() {
+	for {
+		m.mu.Lock()
+		for len(m.queue) == 0 && !m.closed {
+			m.ready.Wait()
+		}
+		if m.closed {
+			m.mu.Unlock()
+			return
+		}
+		j := m.queue[0]
+		m.queue = m.queue[1:]
+		j.snap.Status = StatusRunning
+		j.snap.StartedAt = time.Now()
+		m.mu.Unlock()
+
+		result, err := j.fn(j.ctx)
+		j.cancel()
+
+		m.mu.Lock()
+		if j.snap.Status == StatusRunning {
+			switch {
+			case m.ctx.Err() != nil:
+				// Interrupted by Close.
+				m.finish(j, StatusCanceled, time.Now())
+			case err != nil:
+				j.snap.Err = err
+				m.finish(j, StatusFailed, time.Now())
+			default:
+				j.snap.Result = result
+				m.finish(j, StatusDone, time.Now())
+			}
+		}
+		m.mu.Unlock()
+	}
+}
+
+// sweep forgets expired jobs every half TTL, so a finished job is held in
+// memory for at most one and a half TTLs. It runs at most once a second,
+// however short the TTL.
+func (m *Manager) sweep() {
+	t := time.NewTicker(max(m.cfg.TTL/2, time.Second))
+	defer t.Stop()
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case now := <-t.C:
+			m.mu.Lock()
+			for id, j := range m.jobs {
+				if j.expired(now) {
+					delete(m.jobs, id)
+				}
+			}
+			m.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func closeManager(t *testing.T, m *Manager) {
+	t.Helper()
+	t.Cleanup(func() {
+		if err := m.Close(context.Background()); err != nil {
+			t.Errorf("Close: %v", err)
+		}
+	})
+}
+
+// wait polls the job until it has a final status.
+func wait(t *testing.T, m *Manager, id string) Snapshot {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		snap, err := m.Get(id)
+		if err != nil {
+			t.Fatalf("Get(%s): %v", id, err)
+		}
+		if snap.Status.Finished() {
+			return snap
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return Snapshot{}
+}
+
+// blocker returns a Func that runs until release is closed or its context
+// is done, and a channel receiving a value once it started.
+func blocker(release <-chan struct{}) (Func, <-chan struct{}) {
+	started := make(chan struct{})
+	return func(ctx context.Context) (any, error) {
+		close(started)
+		select {
+		case <-release:
+			return "released", nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}, started
+}
+
+func TestManager_Results(t *testing.T) {
+	m := New(Config{Workers: 2})
+	closeManager(t, m)
+
+	ok, err := m.Submit(func(context.Context) (any, error) { return 42, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok.Status != StatusQueued || ok.ID == "" {
+		t.Errorf("submitted job = %+v, want a queued job with an id", ok)
+	}
+	boom := errors.New("boom")
+	failed, err := m.Submit(func(context.Context) (any, error) { return nil, boom })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if snap := wait(t, m, ok.ID); snap.Status != StatusDone || snap.Result != 42 {
+		t.Errorf("job = %s with %v, want done with 42", snap.Status, snap.Result)
+	}
+	snap := wait(t, m, failed.ID)
+	if snap.Status != StatusFailed || !errors.Is(snap.Err, boom) {
+		t.Errorf("job = %s with %v, want failed with %v", snap.Status, snap.Err, boom)
+	}
+	if snap.StartedAt.IsZero() || snap.FinishedAt.Before(snap.StartedAt) {
+		t.Errorf("started %v, finished %v", snap.StartedAt, snap.FinishedAt)
+	}
+}
+
+func TestManager_QueueFull(t *testing.T) {
+	m := New(Config{Workers: 1, QueueDepth: 1})
+	closeManager(t, m)
+
+	release := make(chan struct{})
+	defer close(release)
+	fn, started := blocker(release)
+	if _, err := m.Submit(fn); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	queued, _ := blocker(release)
+	second, err := m.Submit(queued)
+	if err != nil {
+		t.Fatalf("second job: %v, want it queued", err)
+	}
+	if _, err := m.Submit(queued); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("third job: %v, want %v", err, ErrQueueFull)
+	}
+
+	// Canceling a queued job frees its place at once.
+	if _, err := m.Cancel(second.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Submit(queued); err != nil {
+		t.Errorf("job after cancel: %v, want it queued", err)
+	}
+}
+
+func TestManager_Cancel(t *testing.T) {
+	m := New(Config{Workers: 1})
+	closeManager(t, m)
+
+	release := make(chan struct{})
+	defer close(release)
+	fn, started := blocker(release)
+	running, _ := m.Submit(fn)
+	<-started
+	queued, _ := m.Submit(fn)
+
+	for _, id := range []string{queued.ID, running.ID} {
+		snap, err := m.Cancel(id)
+		if err != nil {
+			t.Fatalf("Cancel(%s): %v", id, err)
+		}
+		if snap.Status != StatusCanceled {
+			t.Errorf("status = %s, want canceled", snap.Status)
+		}
+	}
+
+	// The worker is free again once the running job noticed.
+	done, _ := m.Submit(func(context.Context) (any, error) { return nil, nil })
+	if snap := wait(t, m, done.ID); snap.Status != StatusDone {
+		t.Errorf("status = %s, want done", snap.Status)
+	}
+
+	if _, err := m.Cancel(done.ID); !errors.Is(err, ErrFinished) {
+		t.Errorf("Cancel of a finished job: %v, want %v", err, ErrFinished)
+	}
+	if _, err := m.Cancel("nope"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Cancel of an unknown job: %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestManager_TTL(t *testing.T) {
+	m := New(Config{TTL: 20 * time.Millisecond})
+	closeManager(t, m)
+
+	job, _ := m.Submit(func(context.Context) (any, error) { return nil, nil })
+	snap := wait(t, m, job.ID)
+	if want := snap.FinishedAt.Add(20 * time.Millisecond); !snap.ExpiresAt.Equal(want) {
+		t.Errorf("ExpiresAt = %v, want %v", snap.ExpiresAt, want)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if _, err := m.Get(job.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after TTL: %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestManager_TinyTTL(t *testing.T) {
+	// A TTL under 2ns must not give the sweeper a zero interval; Close
+	// waits for the sweeper, so it has started by then.
+	m := New(Config{TTL: time.Nanosecond})
+	if err := m.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func TestManager_Close(t *testing.T) {
+	m := New(Config{Workers: 1})
+
+	fn, started := blocker(nil)
+	running, _ := m.Submit(fn)
+	<-started
+
+	if err := m.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if snap, _ := m.Get(running.ID); snap.Status != StatusCanceled {
+		t.Errorf("running job = %s, want canceled", snap.Status)
+	}
+	if _, err := m.Submit(fn); !errors.Is(err, ErrClosed) {
+		t.Errorf("Submit after Close: %v, want %v", err, ErrClosed)
+	}
+}
@@ -0,0 +1,161 @@
+// Package netguard keeps outgoing HTTP requests away from internal networks.
+//
+// A Guard checks every address an HTTP client connects to, after DNS
+// resolution, so neither a hostname resolving to a private address nor a
+// redirect to one gets through. Loopback, private, link-local (including the
+// 169.254.169.254 cloud metadata endpoint), carrier-grade NAT, multicast and
+// reserved ranges are refused unless allowlisted.
+package netguard
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// BlockedError is returned when a connection to a refused address is
+// attempted.
+type BlockedError struct {
+	Addr   netip.Addr
+	Reason string
+}
+
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("connection to %s refused: %s address", e.Addr, e.Reason)
+}
+
+// blockedRanges are refused in addition to what the netip predicates cover,
+// checked in order so the most specific reason is reported.
+var blockedRanges = []struct {
+	prefix netip.Prefix
+	reason string
+}{
+	{netip.MustParsePrefix("169.254.169.254/32"), "metadata"},
+	{netip.MustParsePrefix("fd00:ec2::254/128"), "metadata"},
+	{netip.MustParsePrefix("100.100.100.200/32"), "metadata"},
+	{netip.MustParsePrefix("0.0.0.0/8"), "unspecified"},
+	{netip.MustParsePrefix("100.64.0.0/10"), "shared"},
+	{netip.MustParsePrefix("192.0.0.0/24"), "reserved"},
+	{netip.MustParsePrefix("198.18.0.0/15"), "reserved"},
+	{netip.MustParsePrefix("240.0.0.0/4"), "reserved"},
+}
+
+// Guard refuses connections to non-public addresses. The zero value refuses
+// them all; use New to allowlist some.
+type Guard struct {
+	prefixes []netip.Prefix
+	hosts    []string
+}
+
+// New returns a Guard that lets through the given allowlist entries. Each
+// is an IP address, a CIDR prefix such as "10.20.0.0/16", a hostname, or a
+// "*.example.com" wildcard matching any subdomain. Allowlisted hostnames are
+// connected to without checking the addresses they resolve to.
+func New(allow []string) (*Guard, error) {
+	g := &Guard{}
+	for _, entry := range allow {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if p, err := netip.ParsePrefix(entry); err == nil {
+			g.prefixes = append(g.prefixes, p.Masked())
+			continue
+		}
+		if a, err := netip.ParseAddr(entry); err == nil {
+			g.prefixes = append(g.prefixes, netip.PrefixFrom(a, a.BitLen()))
+			continue
+		}
+		if strings.ContainsAny(entry, "/:") {
+			return nil, fmt.Errorf("invalid allowlist entry %q", entry)
+		}
+		g.hosts = append(g.hosts, strings.TrimSuffix(entry, "."))
+	}
+	return g, nil
+}
+
+// Check returns a *BlockedError if addr may not be connected to.
+func (g *Guard) Check(addr netip.Addr) error {
+	addr = addr.Unmap()
+	for _, p := range g.prefixes {
+		if p.Contains(addr) {
+			return nil
+		}
+	}
+	if reason := blockReason(addr); reason != "" {
+		return &BlockedError{Addr: addr, Reason: reason}
+	}
+	return nil
+}
+
+func blockReason(addr netip.Addr) string {
+	for _, r := range blockedRanges {
+		if r.prefix.Contains(addr) {
+			return r.reason
+		}
+	}
+	switch {
+	case addr.IsLoopback():
+		return "loopback"
+	case addr.IsPrivate():
+		return "private"
+	case addr.IsLinkLocalUnicast():
+		return "link-local"
+	case addr.IsUnspecified():
+		return "unspecified"
+	case addr.IsMulticast():
+		return "multicast"
+	}
+	return ""
+}
+
+func (g *Guard) hostAllowed(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, h := range g.hosts {
+		if host == h {
+			return true
+		}
+		if suffix, ok := strings.CutPrefix(h, "*"); ok && strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// DialContext dials like net.Dialer, refusing addresses the guard blocks.
+// The check runs on the resolved address of every connection attempt, so
+// it also applies to each hop of a redirect chain.
+func (g *Guard) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	d := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if host, _, err := net.SplitHostPort(address); err != nil || !g.hostAllowed(host) {
+		d.Control = g.control
+	}
+	return d.DialContext(ctx, network, address)
+}
+
+func (g *Guard) control(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	return g.Check(addr)
+}
+
+// Transport returns an http.Transport with the defaults of
+// http.DefaultTransport that dials through the guard. Proxies are not
+// used, since the guard would only see the proxy's address.
+func (g *Guard) Transport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = nil
+	t.DialContext = g.DialContext
+	return t
+}
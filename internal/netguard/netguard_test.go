@@ -0,0 +1,115 @@
+package netguard
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestGuard_Check(t *testing.T) {
+	guard, err := New([]string{"10.20.0.0/16", "192.168.1.5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		addr   string
+		reason string
+	}{
+		{"93.184.215.14", ""},
+		{"2606:2800:21f:cb07:6820:80da:af6b:8b2c", ""},
+		{"127.0.0.1", "loopback"},
+		{"::1", "loopback"},
+		{"::ffff:127.0.0.1", "loopback"},
+		{"10.0.0.1", "private"},
+		{"172.16.5.4", "private"},
+		{"192.168.0.1", "private"},
+		{"fd12:3456::1", "private"},
+		{"169.254.169.254", "metadata"},
+		{"fd00:ec2::254", "metadata"},
+		{"169.254.1.1", "link-local"},
+		{"fe80::1", "link-local"},
+		{"0.0.0.0", "unspecified"},
+		{"::", "unspecified"},
+		{"100.64.0.1", "shared"},
+		{"224.0.0.1", "multicast"},
+		{"255.255.255.255", "reserved"},
+		// Allowlisted.
+		{"10.20.3.4", ""},
+		{"192.168.1.5", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			err := guard.Check(netip.MustParseAddr(tt.addr))
+			var blocked *BlockedError
+			switch {
+			case tt.reason == "" && err != nil:
+				t.Errorf("Check() = %v, want allowed", err)
+			case tt.reason != "" && !errors.As(err, &blocked):
+				t.Errorf("Check() = %v, want blocked as %s", err, tt.reason)
+			case tt.reason != "" && blocked.Reason != tt.reason:
+				t.Errorf("reason = %q, want %q", blocked.Reason, tt.reason)
+			}
+		})
+	}
+}
+
+func TestNew_InvalidEntry(t *testing.T) {
+	if _, err := New([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("New() with invalid prefix: got nil error")
+	}
+}
+
+func TestGuard_Transport(t *testing.T) {
+	var target *httptest.Server
+	target = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			// Same server, but by IP address rather than hostname.
+			http.Redirect(w, r, target.URL+"/ok", http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+	byName := strings.Replace(target.URL, "127.0.0.1", "localhost", 1)
+
+	tests := []struct {
+		name    string
+		allow   []string
+		url     string
+		blocked bool
+	}{
+		{name: "loopback refused", url: target.URL + "/ok", blocked: true},
+		{name: "loopback allowlisted", allow: []string{"127.0.0.0/8"}, url: target.URL + "/ok"},
+		{name: "hostname allowlisted", allow: []string{"localhost"}, url: byName + "/ok"},
+		{name: "wildcard does not match the bare domain", allow: []string{"*.localhost"}, url: byName + "/ok", blocked: true},
+		{name: "redirect re-checked", allow: []string{"localhost"}, url: byName + "/redirect", blocked: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guard, err := New(tt.allow)
+			if err != nil {
+				t.Fatal(err)
+			}
+			client := &http.Client{Transport: guard.Transport()}
+
+			resp, err := client.Get(tt.url)
+			if err == nil {
+				resp.Body.Close()
+			}
+
+			var blocked *BlockedError
+			if got := errors.As(err, &blocked); got != tt.blocked {
+				t.Errorf("blocked = %v (err %v), want %v", got, err, tt.blocked)
+			}
+			if !tt.blocked && err != nil {
+				t.Errorf("Get() error: %v", err)
+			}
+		})
+	}
+}